@@ -6,10 +6,13 @@ Description: This is the main entry point of the server application. It initiali
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/akhilckenshi/notification/internal/database"
 	routers "github.com/akhilckenshi/notification/internal/routes"
@@ -18,6 +21,9 @@ import (
 	cfg "github.com/akhilckenshi/notification/pkg/settings"
 )
 
+// shutdownTimeout bounds the wait for background workers on shutdown
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	// Initialize application configuration from environment variables and settings files
 	config, err := initializeConfig()
@@ -39,8 +45,13 @@ func main() {
 		}
 	}()
 
+	// Context shared with background workers so they stop on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Initialize the HTTP router with the registered routes
-	router := routers.GetRouter()
+	var background sync.WaitGroup
+	router := routers.GetRouter(ctx, &background)
 	logger.Log.Info("Router Initialized")
 
 	// Signal handling for graceful shutdown
@@ -66,6 +77,19 @@ func main() {
 	// Wait for a termination signal before gracefully closing services
 	<-quit
 	logger.Log.Info("Shutting down server...")
+	cancel()
+
+	// Let the workers finish their current notification before the database is closed
+	stopped := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		logger.Log.Warn("Background workers did not stop in time")
+	}
 
 	logger.Log.Info("Server gracefully stopped.")
 }
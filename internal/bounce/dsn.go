@@ -0,0 +1,232 @@
+/*
+bounce/dsn.go
+Author: Akhil C
+Description: Parses delivery status notifications (RFC 3464) and abuse feedback reports (RFC 5965) received for sent emails into bounce and complaint events.
+*/
+
+package bounce
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// Event types
+const (
+	TypeBounce    = "bounce"
+	TypeComplaint = "complaint"
+)
+
+// Bounce types reported by email services instead of an enhanced status code
+const (
+	BounceHard = "hard" // Permanent failure (SES Permanent)
+	BounceSoft = "soft" // Temporary failure (SES Transient)
+)
+
+// ErrNotAReport is returned when a message is neither a delivery status notification nor a feedback report
+var ErrNotAReport = errors.New("message is not a delivery status or feedback report")
+
+// Event is a bounce or complaint for one recipient of a sent email
+type Event struct {
+	Type       string `json:"type"`                  // TypeBounce or TypeComplaint
+	Recipient  string `json:"recipient"`             // Address that bounced or complained
+	MessageID  string `json:"message_id,omitempty"`  // Message-ID (or provider ID) of the original email
+	Action     string `json:"action,omitempty"`      // DSN action: failed, delayed, delivered, relayed, expanded
+	Status     string `json:"status,omitempty"`      // Enhanced status code (e.g. 5.1.1)
+	Diagnostic string `json:"diagnostic,omitempty"`  // Diagnostic reported by the remote server, or the feedback type
+	BounceType string `json:"bounce_type,omitempty"` // BounceHard or BounceSoft (or permanent, transient), used when Status is empty
+}
+
+// Permanent reports whether the event should stop further emails to the recipient:
+// complaints and hard bounces (failed delivery with a 5.x.x status, or a hard bounce type
+// when the event has no status)
+func (e Event) Permanent() bool {
+	if e.Type == TypeComplaint {
+		return true
+	}
+	if e.Action != "" && !strings.EqualFold(e.Action, "failed") {
+		return false
+	}
+	if e.Status == "" {
+		return strings.EqualFold(e.BounceType, BounceHard) || strings.EqualFold(e.BounceType, "permanent")
+	}
+	return strings.HasPrefix(e.Status, "5")
+}
+
+// Parse reads a multipart/report email (a DSN or an ARF feedback report) and returns one
+// event per recipient it describes. Reports of successful or delayed deliveries are
+// returned too; use Event.Permanent to pick the ones that should suppress the address.
+func Parse(r io.Reader) ([]Event, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" {
+		return nil, ErrNotAReport
+	}
+
+	var perMessage textproto.MIMEHeader
+	var recipients []textproto.MIMEHeader
+	var feedback textproto.MIMEHeader
+	var original textproto.MIMEHeader
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid report: %v", err)
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch partType {
+		case "message/delivery-status", "message/global-delivery-status":
+			groups, err := readFieldGroups(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid delivery status: %v", err)
+			}
+			if len(groups) > 0 {
+				perMessage, recipients = groups[0], groups[1:]
+			}
+		case "message/feedback-report":
+			groups, err := readFieldGroups(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid feedback report: %v", err)
+			}
+			if len(groups) > 0 {
+				feedback = groups[0]
+			}
+		case "message/rfc822", "text/rfc822-headers", "message/global", "message/global-headers":
+			// Only the headers of the original message are needed
+			header, err := textproto.NewReader(bufio.NewReader(part)).ReadMIMEHeader()
+			if err == nil || len(header) > 0 {
+				original = header
+			}
+		}
+	}
+
+	var messageID string
+	if original != nil {
+		messageID = strings.TrimSpace(original.Get("Message-Id"))
+	}
+
+	switch {
+	case feedback != nil:
+		return complaintEvents(feedback, original, messageID), nil
+	case perMessage != nil:
+		return bounceEvents(recipients, messageID), nil
+	default:
+		return nil, ErrNotAReport
+	}
+}
+
+// bounceEvents returns the events of the per-recipient fields of a DSN
+func bounceEvents(recipients []textproto.MIMEHeader, messageID string) []Event {
+	var events []Event
+	for _, fields := range recipients {
+		recipient := addressField(fields.Get("Final-Recipient"))
+		if recipient == "" {
+			recipient = addressField(fields.Get("Original-Recipient"))
+		}
+		if recipient == "" {
+			continue
+		}
+		events = append(events, Event{
+			Type:       TypeBounce,
+			Recipient:  recipient,
+			MessageID:  messageID,
+			Action:     strings.ToLower(strings.TrimSpace(fields.Get("Action"))),
+			Status:     statusCode(fields.Get("Status")),
+			Diagnostic: typedField(fields.Get("Diagnostic-Code")),
+		})
+	}
+	return events
+}
+
+// complaintEvents returns the events of a feedback report. The recipient is taken from
+// Original-Rcpt-To, or from the To header of the original message.
+func complaintEvents(feedback, original textproto.MIMEHeader, messageID string) []Event {
+	recipients := feedback.Values("Original-Rcpt-To")
+	if len(recipients) == 0 && original != nil {
+		if addresses, err := mail.ParseAddressList(original.Get("To")); err == nil {
+			for _, address := range addresses {
+				recipients = append(recipients, address.Address)
+			}
+		}
+	}
+
+	var events []Event
+	for _, recipient := range recipients {
+		events = append(events, Event{
+			Type:       TypeComplaint,
+			Recipient:  addressField(recipient),
+			MessageID:  messageID,
+			Diagnostic: strings.TrimSpace(feedback.Get("Feedback-Type")),
+		})
+	}
+	return events
+}
+
+// readFieldGroups reads the blank line separated groups of header fields of a report part
+func readFieldGroups(r io.Reader) ([]textproto.MIMEHeader, error) {
+	reader := textproto.NewReader(bufio.NewReader(r))
+	var groups []textproto.MIMEHeader
+	for {
+		// Skip blank lines between groups
+		line, err := reader.R.Peek(1)
+		for err == nil && (line[0] == '\r' || line[0] == '\n') {
+			reader.R.ReadByte()
+			line, err = reader.R.Peek(1)
+		}
+		if err == io.EOF {
+			return groups, nil
+		}
+		if err != nil {
+			return groups, err
+		}
+
+		header, err := reader.ReadMIMEHeader()
+		if len(header) > 0 {
+			groups = append(groups, header)
+		}
+		if err == io.EOF {
+			return groups, nil
+		}
+		if err != nil {
+			return groups, err
+		}
+	}
+}
+
+// typedField returns the value of a "type; value" field (e.g. "smtp; 550 5.1.1 User unknown")
+func typedField(value string) string {
+	if i := strings.Index(value, ";"); i >= 0 {
+		value = value[i+1:]
+	}
+	return strings.TrimSpace(value)
+}
+
+// addressField returns the lower case address of an "rfc822; user@example.com" field
+func addressField(value string) string {
+	address := strings.Trim(typedField(value), "<>")
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// statusCode returns the enhanced status code of a Status field, without any comment
+func statusCode(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
@@ -0,0 +1,25 @@
+/*
+controller/metrics.go
+Author: Akhil C
+Description: Controller reporting the state of the delivery pipeline.
+*/
+package controller
+
+import (
+	"github.com/akhilckenshi/notification/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// MetricsController defines HTTP handlers for Metrics.
+type MetricsController struct {
+	service *service.NotificationService
+}
+
+func NewMetricsController(service *service.NotificationService) *MetricsController {
+	return &MetricsController{service: service}
+}
+
+// RateLimits reports the rate limits in use with their current utilization
+func (c *MetricsController) RateLimits(ctx *fiber.Ctx) error {
+	return ctx.JSON(fiber.Map{"rate_limits": c.service.RateLimitStats()})
+}
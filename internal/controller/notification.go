@@ -6,10 +6,15 @@ Description: Controller to manage Notification data in MongoDB.
 package controller
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/repo"
 	"github.com/akhilckenshi/notification/internal/service"
+	"github.com/akhilckenshi/notification/pkg/utils"
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // NotificationController defines HTTP handlers for Notifications.
@@ -45,6 +50,141 @@ func (c *NotificationController) ReadAllNotifications(ctx *fiber.Ctx) error {
 	return ctx.JSON(notificaitons)
 }
 
+// CreateNotification accepts a notification payload, stores it as queued and dispatches it.
+// It responds with the ID of the created notification, or of the original notification when
+// the idempotency key was already used.
+func (c *NotificationController) CreateNotification(ctx *fiber.Ctx) error {
+	var notifier models.Notifier
+	if err := ctx.BodyParser(&notifier); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": utils.InvalidInputErrorMessage})
+	}
+
+	notification, duplicate, err := c.service.SubmitNotification(ctx.Context(), &notifier)
+	if err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// A replayed request returns the original notification instead of sending it again
+	status := fiber.StatusAccepted
+	if duplicate {
+		status = fiber.StatusOK
+	}
+	return ctx.Status(status).JSON(fiber.Map{
+		"id":        notification.ID.Hex(),
+		"status":    notification.Status,
+		"duplicate": duplicate,
+	})
+}
+
+// CreateNotificationBatch accepts a JSON array of notification payloads. Every item is
+// validated and stored independently and the response lists the result of each item in
+// input order. It responds with 202 when all items were accepted and 207 otherwise.
+func (c *NotificationController) CreateNotificationBatch(ctx *fiber.Ctx) error {
+	var notifiers []*models.Notifier
+	if err := ctx.BodyParser(&notifiers); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": utils.InvalidInputErrorMessage})
+	}
+	if len(notifiers) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "at least one notification is required"})
+	}
+
+	results, err := c.service.SubmitNotificationBatch(ctx.Context(), notifiers)
+	if err != nil {
+		var tooLarge *service.BatchTooLargeError
+		if errors.As(err, &tooLarge) {
+			return ctx.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": tooLarge.Error()})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	rejected := 0
+	for _, result := range results {
+		if result.Error != "" {
+			rejected++
+		}
+	}
+
+	status := fiber.StatusAccepted
+	if rejected > 0 {
+		status = fiber.StatusMultiStatus
+	}
+	return ctx.Status(status).JSON(fiber.Map{
+		"accepted": len(results) - rejected,
+		"rejected": rejected,
+		"results":  results,
+	})
+}
+
+// scheduleRequest is the body accepted by the reschedule endpoint
+type scheduleRequest struct {
+	SendAt   string `json:"send_at"`   // New send time: RFC 3339, or local date and time in the time zone of the recipient
+	TimeZone string `json:"time_zone"` // Time zone replacing the one of the notification, optional
+}
+
+// CancelNotification cancels a scheduled notification
+func (c *NotificationController) CancelNotification(ctx *fiber.Ctx) error {
+	orgID, id, err := notificationKey(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	notification, err := c.service.CancelNotification(ctx.Context(), orgID, id)
+	if err != nil {
+		return scheduleError(ctx, err)
+	}
+	return ctx.JSON(notification)
+}
+
+// RescheduleNotification moves a scheduled notification to the send time of the request body
+func (c *NotificationController) RescheduleNotification(ctx *fiber.Ctx) error {
+	orgID, id, err := notificationKey(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	var request scheduleRequest
+	if err := ctx.BodyParser(&request); err != nil || request.SendAt == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": utils.InvalidInputErrorMessage})
+	}
+
+	notification, err := c.service.RescheduleNotification(ctx.Context(), orgID, id, request.SendAt, request.TimeZone)
+	if err != nil {
+		return scheduleError(ctx, err)
+	}
+	return ctx.JSON(notification)
+}
+
+// notificationKey returns the organization (orgID query parameter) and the ID of the notification of a request
+func notificationKey(ctx *fiber.Ctx) (primitive.ObjectID, primitive.ObjectID, error) {
+	orgID, err := primitive.ObjectIDFromHex(ctx.Query("orgID"))
+	if err != nil {
+		return orgID, primitive.NilObjectID, errors.New("organization ID is required")
+	}
+	id, err := primitive.ObjectIDFromHex(ctx.Params("notificationId"))
+	if err != nil {
+		return orgID, id, errors.New("invalid notification ID")
+	}
+	return orgID, id, nil
+}
+
+// scheduleError maps the errors of cancelling and rescheduling notifications to HTTP responses
+func scheduleError(ctx *fiber.Ctx, err error) error {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repo.ErrNotificationNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrNotScheduled):
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
 func Read(data string) string {
 	return fmt.Sprintf("Hello module, I am %s", data)
 }
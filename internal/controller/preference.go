@@ -0,0 +1,101 @@
+/*
+controller/preference.go
+Author: Akhil C
+Description: Controller to manage the notification preferences of recipients.
+*/
+package controller
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"github.com/akhilckenshi/notification/internal/service"
+	"github.com/akhilckenshi/notification/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PreferenceController defines HTTP handlers for Preferences.
+type PreferenceController struct {
+	service *service.PreferenceService
+}
+
+func NewPreferenceController(service *service.PreferenceService) *PreferenceController {
+	return &PreferenceController{service: service}
+}
+
+func (c *PreferenceController) ReadAllPreferences(ctx *fiber.Ctx) error {
+	orgID, err := primitive.ObjectIDFromHex(ctx.Query("orgID"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "organization ID is required"})
+	}
+
+	preferences, err := c.service.ListPreferences(ctx.Context(), orgID)
+	if err != nil {
+		return preferenceError(ctx, err)
+	}
+	return ctx.JSON(preferences)
+}
+
+func (c *PreferenceController) ReadPreference(ctx *fiber.Ctx) error {
+	orgID, err := primitive.ObjectIDFromHex(ctx.Query("orgID"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "organization ID is required"})
+	}
+
+	preference, err := c.service.GetPreference(ctx.Context(), orgID, recipientParam(ctx))
+	if err != nil {
+		return preferenceError(ctx, err)
+	}
+	return ctx.JSON(preference)
+}
+
+// UpdatePreference stores the request body as the preferences of the recipient, replacing the previous ones
+func (c *PreferenceController) UpdatePreference(ctx *fiber.Ctx) error {
+	var preference models.Preference
+	if err := ctx.BodyParser(&preference); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": utils.InvalidInputErrorMessage})
+	}
+	preference.Recipient = recipientParam(ctx)
+
+	if err := c.service.SavePreference(ctx.Context(), &preference); err != nil {
+		return preferenceError(ctx, err)
+	}
+	return ctx.JSON(preference)
+}
+
+func (c *PreferenceController) DeletePreference(ctx *fiber.Ctx) error {
+	orgID, err := primitive.ObjectIDFromHex(ctx.Query("orgID"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "organization ID is required"})
+	}
+
+	if err := c.service.DeletePreference(ctx.Context(), orgID, recipientParam(ctx)); err != nil {
+		return preferenceError(ctx, err)
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// recipientParam returns the recipient path parameter, which may be URL encoded (e.g. %40 for @)
+func recipientParam(ctx *fiber.Ctx) string {
+	recipient := ctx.Params("recipient")
+	if unescaped, err := url.PathUnescape(recipient); err == nil {
+		return unescaped
+	}
+	return recipient
+}
+
+// preferenceError maps preference service errors to HTTP responses
+func preferenceError(ctx *fiber.Ctx, err error) error {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repo.ErrPreferenceNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
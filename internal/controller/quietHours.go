@@ -0,0 +1,79 @@
+/*
+controller/quietHours.go
+Author: Akhil C
+Description: Controller to manage the quiet hours of organizations and recipients.
+*/
+package controller
+
+import (
+	"errors"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"github.com/akhilckenshi/notification/internal/service"
+	"github.com/akhilckenshi/notification/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// QuietHoursController defines HTTP handlers for Quiet Hours.
+type QuietHoursController struct {
+	service *service.QuietHoursService
+}
+
+func NewQuietHoursController(service *service.QuietHoursService) *QuietHoursController {
+	return &QuietHoursController{service: service}
+}
+
+func (c *QuietHoursController) ReadAllQuietHours(ctx *fiber.Ctx) error {
+	orgID, err := primitive.ObjectIDFromHex(ctx.Query("orgID"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "organization ID is required"})
+	}
+
+	quietHours, err := c.service.ListQuietHours(ctx.Context(), orgID)
+	if err != nil {
+		return quietHoursError(ctx, err)
+	}
+	return ctx.JSON(quietHours)
+}
+
+// UpdateQuietHours stores the request body as the quiet hours of the organization, or of the
+// recipient of the path, replacing the previous ones
+func (c *QuietHoursController) UpdateQuietHours(ctx *fiber.Ctx) error {
+	var quietHours models.QuietHours
+	if err := ctx.BodyParser(&quietHours); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": utils.InvalidInputErrorMessage})
+	}
+	quietHours.Recipient = recipientParam(ctx)
+
+	if err := c.service.SaveQuietHours(ctx.Context(), &quietHours); err != nil {
+		return quietHoursError(ctx, err)
+	}
+	return ctx.JSON(quietHours)
+}
+
+func (c *QuietHoursController) DeleteQuietHours(ctx *fiber.Ctx) error {
+	orgID, err := primitive.ObjectIDFromHex(ctx.Query("orgID"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "organization ID is required"})
+	}
+
+	if err := c.service.DeleteQuietHours(ctx.Context(), orgID, recipientParam(ctx)); err != nil {
+		return quietHoursError(ctx, err)
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// quietHoursError maps quiet hours service errors to HTTP responses
+func quietHoursError(ctx *fiber.Ctx, err error) error {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repo.ErrQuietHoursNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
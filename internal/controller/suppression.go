@@ -0,0 +1,121 @@
+/*
+controller/suppression.go
+Author: Akhil C
+Description: Controller to manage the recipients that notifications must not be sent to.
+*/
+package controller
+
+import (
+	"errors"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"github.com/akhilckenshi/notification/internal/service"
+	"github.com/akhilckenshi/notification/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SuppressionController defines HTTP handlers for Suppressions.
+type SuppressionController struct {
+	service *service.SuppressionService
+}
+
+func NewSuppressionController(service *service.SuppressionService) *SuppressionController {
+	return &SuppressionController{service: service}
+}
+
+func (c *SuppressionController) CreateSuppression(ctx *fiber.Ctx) error {
+	var suppression models.Suppression
+	if err := ctx.BodyParser(&suppression); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": utils.InvalidInputErrorMessage})
+	}
+
+	if err := c.service.AddSuppression(ctx.Context(), &suppression); err != nil {
+		return suppressionError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(suppression)
+}
+
+// ReadAllSuppressions lists the suppressions of an organization, filtered by the channel and recipient query parameters
+func (c *SuppressionController) ReadAllSuppressions(ctx *fiber.Ctx) error {
+	orgID, err := primitive.ObjectIDFromHex(ctx.Query("orgID"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "organization ID is required"})
+	}
+
+	suppressions, err := c.service.ListSuppressions(ctx.Context(), orgID, ctx.Query("channel"), ctx.Query("recipient"))
+	if err != nil {
+		return suppressionError(ctx, err)
+	}
+	return ctx.JSON(suppressions)
+}
+
+func (c *SuppressionController) ReadSuppression(ctx *fiber.Ctx) error {
+	orgID, id, err := suppressionKey(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	suppression, err := c.service.GetSuppression(ctx.Context(), orgID, id)
+	if err != nil {
+		return suppressionError(ctx, err)
+	}
+	return ctx.JSON(suppression)
+}
+
+// UpdateSuppression changes the reason, detail and expiry of a suppression
+func (c *SuppressionController) UpdateSuppression(ctx *fiber.Ctx) error {
+	var suppression models.Suppression
+	if err := ctx.BodyParser(&suppression); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": utils.InvalidInputErrorMessage})
+	}
+	id, err := primitive.ObjectIDFromHex(ctx.Params("suppressionId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid suppression ID"})
+	}
+	suppression.ID = id
+
+	if err := c.service.UpdateSuppression(ctx.Context(), &suppression); err != nil {
+		return suppressionError(ctx, err)
+	}
+	return ctx.JSON(suppression)
+}
+
+func (c *SuppressionController) DeleteSuppression(ctx *fiber.Ctx) error {
+	orgID, id, err := suppressionKey(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := c.service.DeleteSuppression(ctx.Context(), orgID, id); err != nil {
+		return suppressionError(ctx, err)
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// suppressionKey returns the organization (orgID query parameter) and the ID of the suppression of a request
+func suppressionKey(ctx *fiber.Ctx) (primitive.ObjectID, primitive.ObjectID, error) {
+	orgID, err := primitive.ObjectIDFromHex(ctx.Query("orgID"))
+	if err != nil {
+		return orgID, primitive.NilObjectID, errors.New("organization ID is required")
+	}
+	id, err := primitive.ObjectIDFromHex(ctx.Params("suppressionId"))
+	if err != nil {
+		return orgID, id, errors.New("invalid suppression ID")
+	}
+	return orgID, id, nil
+}
+
+// suppressionError maps suppression service errors to HTTP responses
+func suppressionError(ctx *fiber.Ctx, err error) error {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repo.ErrSuppressionNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
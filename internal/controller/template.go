@@ -0,0 +1,160 @@
+/*
+controller/template.go
+Author: Akhil C
+Description: Controller to manage notification templates and preview their rendering.
+*/
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"github.com/akhilckenshi/notification/internal/service"
+	"github.com/akhilckenshi/notification/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TemplateController defines HTTP handlers for Templates.
+type TemplateController struct {
+	service *service.TemplateService
+}
+
+func NewTemplateController(service *service.TemplateService) *TemplateController {
+	return &TemplateController{service: service}
+}
+
+// previewRequest is the body accepted by the preview endpoint
+type previewRequest struct {
+	Channel string         `json:"channel"` // Variant to render (email, whatsapp)
+	Locale  string         `json:"locale"`  // Recipient locale used to pick the variant
+	Version int            `json:"version"` // Version to render, latest when empty
+	Data    map[string]any `json:"data"`    // Values available to the template
+}
+
+func (c *TemplateController) CreateTemplate(ctx *fiber.Ctx) error {
+	var template models.Template
+	if err := ctx.BodyParser(&template); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": utils.InvalidInputErrorMessage})
+	}
+
+	if err := c.service.CreateTemplate(ctx.Context(), &template); err != nil {
+		return templateError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(template)
+}
+
+func (c *TemplateController) ReadAllTemplates(ctx *fiber.Ctx) error {
+	orgID, err := primitive.ObjectIDFromHex(ctx.Query("orgID"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "organization ID is required"})
+	}
+
+	templates, err := c.service.ListTemplates(ctx.Context(), orgID)
+	if err != nil {
+		return templateError(ctx, err)
+	}
+	return ctx.JSON(templates)
+}
+
+// ReadTemplate returns the latest version of a template, or the version given in the version query parameter
+func (c *TemplateController) ReadTemplate(ctx *fiber.Ctx) error {
+	orgID, err := primitive.ObjectIDFromHex(ctx.Query("orgID"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "organization ID is required"})
+	}
+	version, err := strconv.Atoi(ctx.Query("version", "0"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "version must be a number"})
+	}
+
+	template, err := c.service.GetTemplate(ctx.Context(), orgID, ctx.Params("templateId"), version)
+	if err != nil {
+		return templateError(ctx, err)
+	}
+	return ctx.JSON(template)
+}
+
+func (c *TemplateController) ReadTemplateVersions(ctx *fiber.Ctx) error {
+	orgID, err := primitive.ObjectIDFromHex(ctx.Query("orgID"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "organization ID is required"})
+	}
+
+	templates, err := c.service.ListTemplateVersions(ctx.Context(), orgID, ctx.Params("templateId"))
+	if err != nil {
+		return templateError(ctx, err)
+	}
+	return ctx.JSON(templates)
+}
+
+// UpdateTemplate stores the request body as a new version of the template
+func (c *TemplateController) UpdateTemplate(ctx *fiber.Ctx) error {
+	var template models.Template
+	if err := ctx.BodyParser(&template); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": utils.InvalidInputErrorMessage})
+	}
+	template.TemplateID = ctx.Params("templateId")
+
+	if err := c.service.UpdateTemplate(ctx.Context(), &template); err != nil {
+		return templateError(ctx, err)
+	}
+	return ctx.JSON(template)
+}
+
+func (c *TemplateController) DeleteTemplate(ctx *fiber.Ctx) error {
+	orgID, err := primitive.ObjectIDFromHex(ctx.Query("orgID"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "organization ID is required"})
+	}
+
+	if err := c.service.DeleteTemplate(ctx.Context(), orgID, ctx.Params("templateId")); err != nil {
+		return templateError(ctx, err)
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// PreviewTemplate renders a template variant with the data of the request body without sending anything
+func (c *TemplateController) PreviewTemplate(ctx *fiber.Ctx) error {
+	orgID, err := primitive.ObjectIDFromHex(ctx.Query("orgID"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "organization ID is required"})
+	}
+	var request previewRequest
+	if err := ctx.BodyParser(&request); err != nil || request.Channel == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": utils.InvalidInputErrorMessage})
+	}
+
+	template, rendered, err := c.service.RenderTemplate(ctx.Context(), orgID, ctx.Params("templateId"), request.Version, request.Channel, request.Locale, request.Data)
+	if err != nil {
+		if errors.Is(err, repo.ErrTemplateNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		// Any other error comes from rendering the template with the given data
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{
+		"template_id": template.TemplateID,
+		"version":     template.Version,
+		"channel":     request.Channel,
+		"locale":      rendered.Locale,
+		"rendered":    rendered,
+	})
+}
+
+// templateError maps template service errors to HTTP responses
+func templateError(ctx *fiber.Ctx, err error) error {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repo.ErrTemplateNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrTemplateExists):
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
@@ -0,0 +1,58 @@
+/*
+controller/unsubscribe.go
+Author: Akhil C
+Description: Controller for the public unsubscribe endpoint linked from marketing emails (RFC 8058 one-click unsubscribe).
+*/
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+
+	"github.com/akhilckenshi/notification/internal/service"
+	"github.com/akhilckenshi/notification/internal/unsubscribe"
+	"github.com/gofiber/fiber/v2"
+)
+
+// UnsubscribeController defines HTTP handlers for unsubscribe links.
+type UnsubscribeController struct {
+	service *service.PreferenceService
+}
+
+func NewUnsubscribeController(service *service.PreferenceService) *UnsubscribeController {
+	return &UnsubscribeController{service: service}
+}
+
+// ConfirmUnsubscribe shows a page asking the recipient to confirm. Opening the link does not
+// unsubscribe, so that link scanners of mail servers cannot unsubscribe recipients.
+func (c *UnsubscribeController) ConfirmUnsubscribe(ctx *fiber.Ctx) error {
+	action := unsubscribe.Path + "?token=" + url.QueryEscape(ctx.Query("token"))
+	return unsubscribePage(ctx, fiber.StatusOK, fmt.Sprintf(
+		`<p>Do you want to stop receiving these emails?</p><form method="post" action="%s"><button type="submit">Unsubscribe</button></form>`,
+		html.EscapeString(action),
+	))
+}
+
+// Unsubscribe opts the recipient of the token out of the category of the email. Mail clients
+// post List-Unsubscribe=One-Click to this endpoint; the confirmation form posts it too.
+func (c *UnsubscribeController) Unsubscribe(ctx *fiber.Ctx) error {
+	claims, err := c.service.Unsubscribe(ctx.Context(), ctx.Query("token"))
+	switch {
+	case err == nil:
+		return unsubscribePage(ctx, fiber.StatusOK, fmt.Sprintf(
+			"<p>%s will no longer receive %s emails.</p>", html.EscapeString(claims.Recipient), html.EscapeString(claims.Category),
+		))
+	case errors.Is(err, unsubscribe.ErrInvalidToken):
+		return unsubscribePage(ctx, fiber.StatusBadRequest, "<p>This unsubscribe link is invalid.</p>")
+	default:
+		return unsubscribePage(ctx, fiber.StatusInternalServerError, "<p>We could not process your request, please try again later.</p>")
+	}
+}
+
+// unsubscribePage responds with a minimal HTML page
+func unsubscribePage(ctx *fiber.Ctx, status int, body string) error {
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return ctx.Status(status).SendString("<!DOCTYPE html><html><head><title>Unsubscribe</title></head><body>" + body + "</body></html>")
+}
@@ -0,0 +1,145 @@
+/*
+controller/webhook.go
+Author: Akhil C
+Description: Controller for provider webhooks reporting the delivery status, bounces and complaints of sent notifications, and the middlewares authenticating them.
+*/
+package controller
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/akhilckenshi/notification/internal/bounce"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"github.com/akhilckenshi/notification/internal/service"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	cfg "github.com/akhilckenshi/notification/pkg/settings"
+	"github.com/gofiber/fiber/v2"
+	twclient "github.com/twilio/twilio-go/client"
+)
+
+// WebhookController defines HTTP handlers for provider webhooks.
+type WebhookController struct {
+	service *service.NotificationService
+}
+
+func NewWebhookController(service *service.NotificationService) *WebhookController {
+	return &WebhookController{service: service}
+}
+
+// TwilioStatus receives Twilio message status callbacks and updates the matching notification
+func (c *WebhookController) TwilioStatus(ctx *fiber.Ctx) error {
+	update := service.TwilioStatusUpdate{
+		MessageSID:   ctx.FormValue("MessageSid"),
+		Status:       ctx.FormValue("MessageStatus"),
+		ErrorMessage: ctx.FormValue("ErrorMessage"),
+	}
+	if code := ctx.FormValue("ErrorCode"); code != "" {
+		update.ErrorCode, _ = strconv.Atoi(code)
+	}
+
+	err := c.service.HandleTwilioStatus(ctx.Context(), update)
+	var validationErr *service.ValidationError
+	switch {
+	case err == nil:
+		return ctx.SendStatus(fiber.StatusNoContent)
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, repo.ErrNotificationNotFound):
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// EmailBounce receives bounce and complaint events as JSON, either a single event or an array
+func (c *WebhookController) EmailBounce(ctx *fiber.Ctx) error {
+	body := bytes.TrimSpace(ctx.Body())
+	var events []bounce.Event
+	var err error
+	if bytes.HasPrefix(body, []byte("[")) {
+		err = json.Unmarshal(body, &events)
+	} else {
+		var event bounce.Event
+		err = json.Unmarshal(body, &event)
+		events = append(events, event)
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	return c.handleBounces(ctx, events)
+}
+
+// EmailDSN receives a raw delivery status notification or feedback report email
+func (c *WebhookController) EmailDSN(ctx *fiber.Ctx) error {
+	events, err := bounce.Parse(bytes.NewReader(ctx.Body()))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.handleBounces(ctx, events)
+}
+
+// handleBounces applies bounce events and reports how many addresses were suppressed
+func (c *WebhookController) handleBounces(ctx *fiber.Ctx, events []bounce.Event) error {
+	suppressed, err := c.service.HandleBounces(ctx.Context(), events)
+	var validationErr *service.ValidationError
+	switch {
+	case err == nil:
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"events": len(events), "suppressed": suppressed})
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// WebhookToken rejects requests whose X-Webhook-Token header does not match email.webhookToken.
+// Requests are rejected when no token is configured.
+func WebhookToken() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		token := cfg.Config.Email.WebhookToken
+		if token == "" {
+			logger.Log.Error("Rejecting email webhook: email.webhookToken is not configured")
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "webhook token is not configured"})
+		}
+		if subtle.ConstantTimeCompare([]byte(ctx.Get("X-Webhook-Token")), []byte(token)) != 1 {
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "invalid webhook token"})
+		}
+		return ctx.Next()
+	}
+}
+
+// TwilioSignature rejects requests whose X-Twilio-Signature does not match the request URL and
+// form parameters signed with the Twilio auth token. The URL Twilio called is rebuilt from
+// whatsapp.baseUrl when set, so the signature also validates behind a proxy.
+func TwilioSignature() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		authToken := cfg.Config.WhatsAppProviderSecret
+		if authToken == "" {
+			logger.Log.Error("Rejecting Twilio webhook: WHATSAPP_PROVIDER_SECRET is not configured")
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "webhook signature cannot be validated"})
+		}
+
+		requestURI := string(ctx.Request().URI().RequestURI()) // Path and query, even for absolute-form requests
+		url := ctx.BaseURL() + requestURI
+		if baseURL := cfg.Config.WhatsApp.BaseURL; baseURL != "" {
+			url = strings.TrimRight(baseURL, "/") + requestURI
+		}
+		params := make(map[string]string)
+		ctx.Request().PostArgs().VisitAll(func(key, value []byte) {
+			params[string(key)] = string(value)
+		})
+
+		validator := twclient.NewRequestValidator(authToken)
+		if !validator.Validate(url, params, ctx.Get("X-Twilio-Signature")) {
+			logger.Log.Warn(fmt.Sprintf("Rejecting Twilio webhook with invalid signature for %s", url))
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "invalid signature"})
+		}
+		return ctx.Next()
+	}
+}
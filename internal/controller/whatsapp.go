@@ -0,0 +1,39 @@
+/*
+controller/whatsapp.go
+Author: Akhil C
+Description: Controller for the webhook receiving inbound WhatsApp messages from Twilio.
+*/
+package controller
+
+import (
+	"errors"
+
+	"github.com/akhilckenshi/notification/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// emptyTwiML acknowledges a Twilio webhook without replying to the message
+const emptyTwiML = `<?xml version="1.0" encoding="UTF-8"?><Response></Response>`
+
+// WhatsAppController defines HTTP handlers for WhatsApp webhooks.
+type WhatsAppController struct {
+	service *service.WhatsAppService
+}
+
+func NewWhatsAppController(service *service.WhatsAppService) *WhatsAppController {
+	return &WhatsAppController{service: service}
+}
+
+// Inbound receives the messages sent by recipients to the WhatsApp number and opens their session window
+func (c *WhatsAppController) Inbound(ctx *fiber.Ctx) error {
+	if err := c.service.RecordInbound(ctx.Context(), ctx.FormValue("From")); err != nil {
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextXMLCharsetUTF8)
+	return ctx.SendString(emptyTwiML)
+}
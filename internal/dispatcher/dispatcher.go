@@ -0,0 +1,258 @@
+/*
+dispatcher/dispatcher.go
+Author: Akhil C
+Description: Priority work queues served by reserved workers per priority and by shared workers picking queues with weighted fair scheduling.
+*/
+
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	cfg "github.com/akhilckenshi/notification/pkg/settings"
+)
+
+// Priorities of the work queues, from the most to the least urgent
+const (
+	PriorityHigh   = "high"
+	PriorityMedium = "medium"
+	PriorityLow    = "low"
+)
+
+// Priorities lists the priorities from the most to the least urgent
+var Priorities = []string{PriorityHigh, PriorityMedium, PriorityLow}
+
+// Defaults applied when the dispatcher or a priority has no configuration
+const (
+	defaultSharedWorkers = 8
+	defaultQueueSize     = 1000
+)
+
+// defaultPriorities are the reserved workers and weights of priorities that are not configured
+var defaultPriorities = map[string]cfg.DispatchPriorityConfig{
+	PriorityHigh:   {Workers: 2, Weight: 6},
+	PriorityMedium: {Workers: 1, Weight: 3},
+	PriorityLow:    {Workers: 0, Weight: 1},
+}
+
+// ErrStopped is returned when submitting work to a dispatcher that is not running
+var ErrStopped = errors.New("dispatcher is stopped")
+
+// Job is a unit of work run by a worker of the dispatcher
+type Job func()
+
+// Options configures a Dispatcher
+type Options struct {
+	SharedWorkers int                                   // Workers serving every priority according to the weights
+	QueueSize     int                                   // Jobs waiting per priority before Submit blocks
+	Priorities    map[string]cfg.DispatchPriorityConfig // Reserved workers and weight of each priority
+}
+
+// Dispatcher runs jobs from one queue per priority. Each priority has workers reserved to its
+// queue, so a backlog of low priority jobs never takes the workers of urgent ones. Shared workers
+// serve every queue, picking the next queue by smooth weighted round robin: under contention a
+// priority of weight 6 gets six jobs started for every job of a priority of weight 1.
+type Dispatcher struct {
+	options Options
+	queues  map[string]chan Job
+	done    chan struct{} // Closed once the dispatcher stops
+
+	mu      sync.Mutex
+	current map[string]int // Smooth weighted round robin state of the shared workers
+	wg      sync.WaitGroup
+	started bool
+}
+
+// New creates a dispatcher; missing options are replaced by defaults
+func New(options Options) *Dispatcher {
+	if options.SharedWorkers < 0 {
+		options.SharedWorkers = 0
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = defaultQueueSize
+	}
+	priorities := map[string]cfg.DispatchPriorityConfig{}
+	queues := map[string]chan Job{}
+	for _, priority := range Priorities {
+		priorityOptions, ok := options.Priorities[priority]
+		if !ok {
+			priorityOptions = defaultPriorities[priority]
+		}
+		if priorityOptions.Workers < 0 {
+			priorityOptions.Workers = 0
+		}
+		if priorityOptions.Weight <= 0 {
+			priorityOptions.Weight = 1
+		}
+		priorities[priority] = priorityOptions
+		queues[priority] = make(chan Job, options.QueueSize)
+	}
+	options.Priorities = priorities
+
+	return &Dispatcher{options: options, queues: queues, done: make(chan struct{}), current: map[string]int{}}
+}
+
+// NewFromConfig creates a dispatcher from the dispatcher configuration
+func NewFromConfig() *Dispatcher {
+	conf := cfg.Config.Dispatcher
+	workers := conf.Workers
+	if workers == 0 {
+		workers = defaultSharedWorkers
+	}
+	priorities := map[string]cfg.DispatchPriorityConfig{}
+	for priority, priorityConfig := range conf.Priorities {
+		priorities[strings.ToLower(priority)] = priorityConfig
+	}
+	return New(Options{SharedWorkers: workers, QueueSize: conf.QueueSize, Priorities: priorities})
+}
+
+// Normalize returns the queue of a priority; empty and unknown priorities are medium
+func Normalize(priority string) string {
+	priority = strings.ToLower(strings.TrimSpace(priority))
+	if _, ok := defaultPriorities[priority]; ok {
+		return priority
+	}
+	return PriorityMedium
+}
+
+// Start starts the workers, which stop once ctx is cancelled. Jobs still queued then are not run.
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.started {
+		return
+	}
+	d.started = true
+
+	for _, priority := range Priorities {
+		for i := 0; i < d.options.Priorities[priority].Workers; i++ {
+			d.wg.Add(1)
+			go d.reservedWorker(ctx, d.queues[priority])
+		}
+	}
+	for i := 0; i < d.options.SharedWorkers; i++ {
+		d.wg.Add(1)
+		go d.sharedWorker(ctx)
+	}
+	go func() {
+		<-ctx.Done()
+		close(d.done)
+	}()
+}
+
+// Wait blocks until every worker has stopped
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// Submit queues a job with the given priority (see Normalize). It blocks while the queue of the
+// priority is full, until ctx is cancelled or the dispatcher stops.
+func (d *Dispatcher) Submit(ctx context.Context, priority string, job Job) error {
+	select {
+	case <-d.done:
+		return ErrStopped
+	default:
+	}
+	select {
+	case d.queues[Normalize(priority)] <- job:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.done:
+		return ErrStopped
+	}
+}
+
+// Pending returns the number of queued jobs per priority
+func (d *Dispatcher) Pending() map[string]int {
+	pending := make(map[string]int, len(d.queues))
+	for priority, queue := range d.queues {
+		pending[priority] = len(queue)
+	}
+	return pending
+}
+
+// reservedWorker runs the jobs of a single queue
+func (d *Dispatcher) reservedWorker(ctx context.Context, queue chan Job) {
+	defer d.wg.Done()
+	for {
+		select {
+		case job := <-queue:
+			job()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sharedWorker runs the jobs of every queue, in weighted fair order
+func (d *Dispatcher) sharedWorker(ctx context.Context) {
+	defer d.wg.Done()
+	for {
+		job, ok := d.next(ctx)
+		if !ok {
+			return
+		}
+		job()
+	}
+}
+
+// next returns the next job for a shared worker, waiting for one when every queue is empty
+func (d *Dispatcher) next(ctx context.Context) (Job, bool) {
+	for {
+		if ctx.Err() != nil {
+			return nil, false
+		}
+		if job, ok := d.pickWeighted(); ok {
+			return job, true
+		}
+		// Nothing queued: the first job of any priority is taken
+		select {
+		case job := <-d.queues[PriorityHigh]:
+			return job, true
+		case job := <-d.queues[PriorityMedium]:
+			return job, true
+		case job := <-d.queues[PriorityLow]:
+			return job, true
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// pickWeighted takes a job from the non-empty queue chosen by smooth weighted round robin. It
+// returns false when every queue is empty.
+func (d *Dispatcher) pickWeighted() (Job, bool) {
+	for {
+		d.mu.Lock()
+		selected, total := "", 0
+		for _, priority := range Priorities {
+			if len(d.queues[priority]) == 0 {
+				d.current[priority] = 0 // An idle queue does not build up credit
+				continue
+			}
+			weight := d.options.Priorities[priority].Weight
+			d.current[priority] += weight
+			total += weight
+			if selected == "" || d.current[priority] > d.current[selected] {
+				selected = priority
+			}
+		}
+		if selected == "" {
+			d.mu.Unlock()
+			return nil, false
+		}
+		d.current[selected] -= total
+		d.mu.Unlock()
+
+		select {
+		case job := <-d.queues[selected]:
+			return job, true
+		default:
+			// Another worker emptied the queue meanwhile
+		}
+	}
+}
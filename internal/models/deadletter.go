@@ -0,0 +1,20 @@
+/*
+models/deadletter.go
+Author: Akhil C
+Description: This file contains the message published to the dead-letter topic for notifications that could not be delivered.
+*/
+
+package models
+
+import "time"
+
+// DeadLetter wraps the original Notifier payload with details about the failed delivery
+type DeadLetter struct {
+	Payload        Notifier  `json:"payload"`         // Original notification payload
+	NotificationID string    `json:"notification_id"` // ID of the stored notification
+	Type           string    `json:"type"`            // Notification type (channel)
+	Attempts       int       `json:"attempts"`        // Number of send attempts made
+	Error          string    `json:"error"`           // Error returned by the last attempt
+	Retryable      bool      `json:"retryable"`       // Whether the last error was classified as retryable
+	FailedAt       time.Time `json:"failed_at"`       // Timestamp of the final failure
+}
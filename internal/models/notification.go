@@ -7,24 +7,151 @@ Description: This file contains the document model and related structures for do
 package models
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Notification struct {
-	ID             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`      // Unique identifier for the Notification
-	NotificationID primitive.ObjectID `json:"notification_id" bson:"notification_id"` // ID of the notification
-	OrganizationID primitive.ObjectID `json:"organization_id" bson:"organization_id"` // ID of the organization
-	To             string             `json:"to" bson:"to"`                           // Recipient of the notification
-	From           string             `json:"from" bson:"from"`                       // Sender of the notification
-	Type           string             `json:"type" bson:"type"`                       // Type of the notification message
-	Priority       string             `json:"priority" bson:"priority"`               // Priority level of the notification
-	Subject        string             `json:"subject" bson:"subject"`                 // Subject of the notification message
-	Message        string             `json:"message" bson:"message"`                 // Content of the notification message
-	Status         string             `json:"status" bson:"status"`                   // Current status of the notification (e.g., sent, pending)
-	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`           // Timestamp of when the notification was created
-	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`           // Timestamp of when the notification was last updated
+	ID              primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`                            // Unique identifier for the Notification
+	NotificationID  primitive.ObjectID `json:"notification_id" bson:"notification_id"`                       // ID of the notification
+	OrganizationID  primitive.ObjectID `json:"organization_id" bson:"organization_id"`                       // ID of the organization
+	To              string             `json:"to" bson:"to"`                                                 // Recipient of the notification
+	From            string             `json:"from" bson:"from"`                                             // Sender of the notification
+	Type            string             `json:"type" bson:"type"`                                             // Type of the notification message
+	Priority        string             `json:"priority" bson:"priority"`                                     // Priority level of the notification
+	Category        string             `json:"category,omitempty" bson:"category,omitempty"`                 // Category the recipient preferences are applied to (see Category* constants)
+	Subject         string             `json:"subject" bson:"subject"`                                       // Subject of the notification message
+	Message         string             `json:"message" bson:"message"`                                       // Content of the notification message
+	PlainText       string             `json:"plain_text,omitempty" bson:"plain_text,omitempty"`             // Plain text alternative of an HTML message
+	Cc              []string           `json:"cc,omitempty" bson:"cc,omitempty"`                             // Carbon copy recipients (email)
+	Bcc             []string           `json:"bcc,omitempty" bson:"bcc,omitempty"`                           // Blind carbon copy recipients (email)
+	ReplyTo         string             `json:"reply_to,omitempty" bson:"reply_to,omitempty"`                 // Reply-To address (email)
+	Attachments     []Attachment       `json:"attachments,omitempty" bson:"attachments,omitempty"`           // Attachments and inline images (email)
+	TemplateID      string             `json:"template_id,omitempty" bson:"template_id,omitempty"`           // Template rendered into Subject and Message at send time
+	TemplateVersion int                `json:"template_version,omitempty" bson:"template_version,omitempty"` // Template version requested, or rendered once sent
+	Data            map[string]any     `json:"data,omitempty" bson:"data,omitempty"`                         // Values available to the template
+	Locale          string             `json:"locale,omitempty" bson:"locale,omitempty"`                     // Recipient locale used to pick the template variant
+	Status          string             `json:"status" bson:"status"`                                         // Current status of the notification (see Status* constants)
+	StatusHistory   []StatusTransition `json:"status_history" bson:"status_history"`                         // Timestamped status transitions, oldest first
+	Attempts        int                `json:"attempts" bson:"attempts"`                                     // Number of send attempts made so far
+	Provider        string             `json:"provider,omitempty" bson:"provider,omitempty"`                 // Provider that delivered the notification (e.g. smtp, ses, twilio)
+	Segments        int                `json:"segments,omitempty" bson:"segments,omitempty"`                 // Number of segments an SMS was billed as
+	IdempotencyKey  string             `json:"idempotency_key,omitempty" bson:"idempotency_key,omitempty"`   // Key used to detect duplicate submissions
+	DedupExpiresAt  time.Time          `json:"dedup_expires_at,omitempty" bson:"dedup_expires_at,omitempty"` // End of the window in which the idempotency key is enforced
+	CreatedAt       time.Time          `json:"created_at" bson:"created_at"`                                 // Timestamp of when the notification was created
+	UpdatedAt       time.Time          `json:"updated_at" bson:"updated_at"`                                 // Timestamp of when the notification was last updated
+	Payload         *Notifier          `json:"-" bson:"-"`                                                   // Original payload the notification was created from (not persisted)
+
+	// WhatsApp content template sent outside of the session window, and media of free-form messages
+	ContentSID       string            `json:"content_sid,omitempty" bson:"content_sid,omitempty"`             // SID of the approved content template (HX...)
+	ContentVariables map[string]string `json:"content_variables,omitempty" bson:"content_variables,omitempty"` // Values of the template placeholders, keyed by position ("1", "2", ...)
+	MediaURLs        []string          `json:"media_urls,omitempty" bson:"media_urls,omitempty"`               // Public URLs of images or documents
+
+	// Details reported by the provider for the last attempt, used to correlate with its console and status callbacks
+	ProviderMessageID string     `json:"provider_message_id,omitempty" bson:"provider_message_id,omitempty"` // Identifier assigned by the provider (e.g. Twilio SID, Message-ID)
+	ProviderStatus    string     `json:"provider_status,omitempty" bson:"provider_status,omitempty"`         // Status reported by the provider
+	ProviderErrorCode int        `json:"provider_error_code,omitempty" bson:"provider_error_code,omitempty"` // Error code reported by the provider
+	ProviderError     string     `json:"provider_error,omitempty" bson:"provider_error,omitempty"`           // Error message reported by the provider
+	ReadAt            *time.Time `json:"read_at,omitempty" bson:"read_at,omitempty"`                         // Timestamp of when the recipient read the message (WhatsApp)
+
+	// Scheduling: the notification is held until SendAt
+	SendAt      *time.Time `json:"send_at,omitempty" bson:"send_at,omitempty"`             // Time the notification is sent at
+	LocalSendAt string     `json:"local_send_at,omitempty" bson:"local_send_at,omitempty"` // Send time requested in the time zone of the recipient, resolved into SendAt when dispatched
+	TimeZone    string     `json:"time_zone,omitempty" bson:"time_zone,omitempty"`         // IANA time zone of the recipient
+
+	// Lease of the worker dispatching the notification, so a notification queued twice is sent once
+	LeaseID        string     `json:"-" bson:"lease_id,omitempty"`         // Random ID of the lease holder
+	LeaseExpiresAt *time.Time `json:"-" bson:"lease_expires_at,omitempty"` // End of the lease; expired leases are taken over
+}
+
+// Attachment is a file sent with an email. Attachments with a ContentID are sent as
+// inline parts that the HTML body can reference as cid:<ContentID>.
+type Attachment struct {
+	Filename    string `json:"filename" bson:"filename"`                             // File name shown to the recipient
+	ContentType string `json:"content_type,omitempty" bson:"content_type,omitempty"` // MIME type, derived from the file name when empty
+	Content     []byte `json:"content" bson:"content"`                               // File content (base64 encoded in JSON)
+	ContentID   string `json:"content_id,omitempty" bson:"content_id,omitempty"`     // Content-ID of an inline image
+}
+
+// Delivery statuses of a notification
+const (
+	StatusQueued     = "queued"     // Accepted and waiting to be sent
+	StatusSending    = "sending"    // Handed to a delivery channel
+	StatusSent       = "sent"       // Accepted by the provider
+	StatusDelivered  = "delivered"  // Confirmed as delivered to the recipient
+	StatusFailed     = "failed"     // Permanently failed
+	StatusRetrying   = "retrying"   // Failed attempt, waiting for another try
+	StatusCancelled  = "cancelled"  // Cancelled before being sent
+	StatusSuppressed = "suppressed" // Not sent because the recipient is suppressed
+	StatusSkipped    = "skipped"    // Not sent because of the preferences of the recipient
+	StatusScheduled  = "scheduled"  // Held until its send time
+)
+
+// statusTransitions lists the statuses reachable from each status
+var statusTransitions = map[string][]string{
+	StatusQueued:     {StatusSending, StatusFailed, StatusCancelled, StatusSuppressed, StatusSkipped, StatusScheduled},
+	StatusSending:    {StatusSent, StatusFailed, StatusRetrying},
+	StatusRetrying:   {StatusSending, StatusFailed, StatusCancelled, StatusSuppressed, StatusSkipped, StatusScheduled},
+	StatusSent:       {StatusDelivered, StatusFailed},
+	StatusDelivered:  {},
+	StatusFailed:     {},
+	StatusCancelled:  {},
+	StatusSuppressed: {},
+	StatusSkipped:    {},
+	StatusScheduled:  {StatusQueued, StatusCancelled},
+}
+
+// PendingStatuses are the statuses of notifications still waiting to be sent. A notification left
+// sending has been interrupted by a worker that stopped.
+var PendingStatuses = []string{StatusQueued, StatusRetrying, StatusSending}
+
+// IsPending reports whether a notification with the given status is still waiting to be sent
+func IsPending(status string) bool {
+	return slices.Contains(PendingStatuses, status)
+}
+
+// Leased reports whether a worker holds an active lease on the notification at the given time
+func (n *Notification) Leased(at time.Time) bool {
+	return n.LeaseExpiresAt != nil && n.LeaseExpiresAt.After(at)
+}
+
+// StatusTransition records a single status change of a notification
+type StatusTransition struct {
+	From   string    `json:"from,omitempty" bson:"from,omitempty"`     // Status before the transition
+	Status string    `json:"status" bson:"status"`                     // Status after the transition
+	Reason string    `json:"reason,omitempty" bson:"reason,omitempty"` // Error or reason that caused the transition
+	At     time.Time `json:"at" bson:"at"`                             // Timestamp of the transition
+}
+
+// CanTransition reports whether a notification may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves the notification to the given status and appends the change to its history.
+// The initial transition of a new notification (empty status) is always allowed.
+func (n *Notification) Transition(status, reason string) (StatusTransition, error) {
+	if n.Status != "" && !CanTransition(n.Status, status) {
+		return StatusTransition{}, fmt.Errorf("invalid status transition from %q to %q", n.Status, status)
+	}
+	transition := StatusTransition{
+		From:   n.Status,
+		Status: status,
+		Reason: reason,
+		At:     time.Now(),
+	}
+	n.Status = status
+	n.UpdatedAt = transition.At
+	n.StatusHistory = append(n.StatusHistory, transition)
+	return transition, nil
 }
 
 func (N Notification) TableName() string {
@@ -32,13 +159,31 @@ func (N Notification) TableName() string {
 }
 
 type Notifier struct {
-	ID             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`      // Unique identifier for the Notification
-	OrganizationID primitive.ObjectID `json:"organization_id" bson:"organization_id"` // ID of the organization
-	To             string             `json:"to" bson:"to"`                           // Reciver of the notification
-	From           string             `json:"from" bson:"from"`                       // Sender of the notification
-	Type           string             `json:"type" bson:"type"`                       // Type of the notification message
-	Priority       string             `json:"priority" bson:"priority"`               // Priority level of the notification
-	Subject        string             `json:"subject" bson:"subject"`                 // Subject of the notification message
-	Message        string             `json:"message" bson:"message"`                 // Content of the notification message
-	CreatedAt      time.Time          `json:"createdAt" bson:"createdAt"`             // Timestamp of when the Business Type was created
+	ID              primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`                            // Unique identifier for the Notification
+	OrganizationID  primitive.ObjectID `json:"organization_id" bson:"organization_id"`                       // ID of the organization
+	To              string             `json:"to" bson:"to"`                                                 // Reciver of the notification
+	From            string             `json:"from" bson:"from"`                                             // Sender of the notification
+	Type            string             `json:"type" bson:"type"`                                             // Type of the notification message
+	Priority        string             `json:"priority" bson:"priority"`                                     // Priority level of the notification
+	Category        string             `json:"category,omitempty" bson:"category,omitempty"`                 // Category the recipient preferences are applied to (see Category* constants)
+	Subject         string             `json:"subject" bson:"subject"`                                       // Subject of the notification message
+	Message         string             `json:"message" bson:"message"`                                       // Content of the notification message
+	PlainText       string             `json:"plain_text,omitempty" bson:"plain_text,omitempty"`             // Plain text alternative of an HTML message (email)
+	Cc              []string           `json:"cc,omitempty" bson:"cc,omitempty"`                             // Carbon copy recipients (email)
+	Bcc             []string           `json:"bcc,omitempty" bson:"bcc,omitempty"`                           // Blind carbon copy recipients (email)
+	ReplyTo         string             `json:"reply_to,omitempty" bson:"reply_to,omitempty"`                 // Reply-To address (email)
+	Attachments     []Attachment       `json:"attachments,omitempty" bson:"attachments,omitempty"`           // Attachments and inline images (email)
+	IdempotencyKey  string             `json:"idempotency_key,omitempty" bson:"idempotency_key,omitempty"`   // Key used to detect duplicates, defaults to ID
+	TemplateID      string             `json:"template_id,omitempty" bson:"template_id,omitempty"`           // Stored template used instead of Subject and Message
+	TemplateVersion int                `json:"template_version,omitempty" bson:"template_version,omitempty"` // Template version, latest when empty
+	Data            map[string]any     `json:"data,omitempty" bson:"data,omitempty"`                         // Values available to the template
+	Locale          string             `json:"locale,omitempty" bson:"locale,omitempty"`                     // Recipient locale (e.g. pt-BR)
+	SendAt          string             `json:"send_at,omitempty" bson:"send_at,omitempty"`                   // Send time: RFC 3339, or local date and time (2006-01-02T15:04) in the time zone of the recipient
+	TimeZone        string             `json:"time_zone,omitempty" bson:"time_zone,omitempty"`               // IANA time zone of the recipient, from its preferences when empty
+	CreatedAt       time.Time          `json:"createdAt" bson:"createdAt"`                                   // Timestamp of when the Business Type was created
+
+	// WhatsApp content template sent outside of the session window, and media of free-form messages
+	ContentSID       string            `json:"content_sid,omitempty" bson:"content_sid,omitempty"`             // SID of the approved content template (HX...)
+	ContentVariables map[string]string `json:"content_variables,omitempty" bson:"content_variables,omitempty"` // Values of the template placeholders, keyed by position ("1", "2", ...)
+	MediaURLs        []string          `json:"media_urls,omitempty" bson:"media_urls,omitempty"`               // Public URLs of images or documents
 }
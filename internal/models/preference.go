@@ -0,0 +1,51 @@
+/*
+models/preference.go
+Author: Akhil C
+Description: This file contains the document model for the notification preferences of a recipient.
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Notification categories. Notifications without category are treated as transactional.
+const (
+	CategoryTransactional = "transactional" // Direct result of an action of the recipient (e.g. receipts)
+	CategorySecurity      = "security"      // Password resets, login alerts, verification codes
+	CategoryBilling       = "billing"       // Invoices, payment reminders
+	CategoryMarketing     = "marketing"     // Newsletters and promotions
+)
+
+// Preference holds which categories of notifications a recipient of an organization accepts,
+// on which channels and at which hours
+type Preference struct {
+	ID             primitive.ObjectID            `json:"id,omitempty" bson:"_id,omitempty"`              // Unique identifier of the preferences
+	OrganizationID primitive.ObjectID            `json:"organization_id" bson:"organization_id"`         // Organization the preferences apply to
+	Recipient      string                        `json:"recipient" bson:"recipient"`                     // Address or phone number, normalized with NormalizeRecipient
+	TimeZone       string                        `json:"time_zone,omitempty" bson:"time_zone,omitempty"` // IANA time zone of the delivery hours, UTC when empty
+	Categories     map[string]CategoryPreference `json:"categories" bson:"categories"`                   // Preferences keyed by category; categories not listed are allowed
+	CreatedAt      time.Time                     `json:"created_at" bson:"created_at"`                   // Timestamp of when the preferences were created
+	UpdatedAt      time.Time                     `json:"updated_at" bson:"updated_at"`                   // Timestamp of when the preferences were last updated
+}
+
+// CategoryPreference restricts the notifications of one category
+type CategoryPreference struct {
+	OptOut   bool           `json:"opt_out" bson:"opt_out"`                       // The recipient does not want the category at all
+	Channels []string       `json:"channels,omitempty" bson:"channels,omitempty"` // Channels the category may be sent on, every channel when empty
+	Hours    *DeliveryHours `json:"hours,omitempty" bson:"hours,omitempty"`       // Local hours the category may be sent at, any time when empty
+}
+
+// DeliveryHours is a daily window in the time zone of the recipient. A window whose end is
+// before its start spans midnight (e.g. 22:00 to 06:00).
+type DeliveryHours struct {
+	Start string `json:"start" bson:"start"` // Start of the window, as HH:MM
+	End   string `json:"end" bson:"end"`     // End of the window (exclusive), as HH:MM
+}
+
+func (P Preference) TableName() string {
+	return "preferences" // Returns the collection name as 'preferences'
+}
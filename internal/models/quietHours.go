@@ -0,0 +1,40 @@
+/*
+models/quietHours.go
+Author: Akhil C
+Description: This file contains the document model for the quiet hours of an organization or of one of its recipients.
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// QuietHours are daily windows during which non-urgent notifications are not sent but deferred
+// to the end of the window. The quiet hours of an organization have no recipient; quiet hours
+// of a recipient replace those of its organization.
+type QuietHours struct {
+	ID                primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`              // Unique identifier of the quiet hours
+	OrganizationID    primitive.ObjectID `json:"organization_id" bson:"organization_id"`         // Organization the quiet hours apply to
+	Recipient         string             `json:"recipient,omitempty" bson:"recipient"`           // Recipient overriding the quiet hours of the organization, normalized with NormalizeRecipient
+	TimeZone          string             `json:"time_zone,omitempty" bson:"time_zone,omitempty"` // IANA time zone of the windows when the notification has none, UTC when empty
+	Windows           []QuietWindow      `json:"windows" bson:"windows"`                         // Quiet windows
+	Channels          []string           `json:"channels,omitempty" bson:"channels,omitempty"`   // Channels the windows apply to, every channel when empty
+	DeferHighPriority bool               `json:"defer_high_priority" bson:"defer_high_priority"` // Also defer high priority notifications, which are sent right away otherwise
+	CreatedAt         time.Time          `json:"created_at" bson:"created_at"`                   // Timestamp of when the quiet hours were created
+	UpdatedAt         time.Time          `json:"updated_at" bson:"updated_at"`                   // Timestamp of when the quiet hours were last updated
+}
+
+// QuietWindow is a daily window. A window whose end is before its start spans midnight and
+// belongs to the day it starts on.
+type QuietWindow struct {
+	Start string   `json:"start" bson:"start"`                   // Start of the window, as HH:MM
+	End   string   `json:"end" bson:"end"`                       // End of the window (exclusive), as HH:MM
+	Days  []string `json:"days,omitempty" bson:"days,omitempty"` // Days the window starts on (mon, tue, ...), every day when empty
+}
+
+func (Q QuietHours) TableName() string {
+	return "quiet_hours" // Returns the collection name as 'quiet_hours'
+}
@@ -0,0 +1,30 @@
+/*
+models/schedule.go
+Author: Akhil C
+Description: This file contains the document model of notifications held until their send time.
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Schedule holds a notification until SendAt. A scheduler claims due schedules with a lease, so
+// that a schedule claimed by a replica that stops before releasing it is claimed again.
+type Schedule struct {
+	ID             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`                            // Unique identifier of the schedule
+	NotificationID primitive.ObjectID `json:"notification_id" bson:"notification_id"`                       // Notification held by the schedule
+	OrganizationID primitive.ObjectID `json:"organization_id" bson:"organization_id"`                       // Organization of the notification
+	SendAt         time.Time          `json:"send_at" bson:"send_at"`                                       // Time the notification is released at
+	LeaseOwner     string             `json:"lease_owner,omitempty" bson:"lease_owner,omitempty"`           // Scheduler instance that claimed the schedule
+	LeaseExpiresAt *time.Time         `json:"lease_expires_at,omitempty" bson:"lease_expires_at,omitempty"` // End of the claim; the schedule can be claimed again afterwards
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`                                 // Timestamp of when the schedule was created
+	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`                                 // Timestamp of when the schedule was last updated
+}
+
+func (S Schedule) TableName() string {
+	return "schedules" // Returns the collection name as 'schedules'
+}
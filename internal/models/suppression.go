@@ -0,0 +1,65 @@
+/*
+models/suppression.go
+Author: Akhil C
+Description: This file contains the document model for recipients that must not receive notifications anymore.
+*/
+
+package models
+
+import (
+	"net/mail"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reasons a recipient is suppressed
+const (
+	SuppressionBounce       = "bounce"       // The address hard bounced
+	SuppressionComplaint    = "complaint"    // The recipient reported a notification as spam
+	SuppressionUnsubscribed = "unsubscribed" // The recipient asked not to be contacted anymore
+	SuppressionManual       = "manual"       // Added through the API
+)
+
+// Suppression prevents notifications of a channel from being sent to a recipient on behalf of the
+// organization it was added for.
+type Suppression struct {
+	ID             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`                          // Unique identifier of the suppression
+	OrganizationID primitive.ObjectID `json:"organization_id,omitempty" bson:"organization_id,omitempty"` // Organization the suppression applies to
+	Channel        string             `json:"channel" bson:"channel"`                                     // Notification type the suppression applies to (email, sms, whatsapp)
+	Recipient      string             `json:"recipient" bson:"recipient"`                                 // Suppressed address or phone number, normalized with NormalizeRecipient
+	Reason         string             `json:"reason" bson:"reason"`                                       // Why the recipient is suppressed (see Suppression* constants)
+	Detail         string             `json:"detail,omitempty" bson:"detail,omitempty"`                   // Diagnostic of the bounce, feedback type of the complaint or a note
+	NotificationID primitive.ObjectID `json:"notification_id,omitempty" bson:"notification_id,omitempty"` // Notification that bounced or was complained about
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`                               // Timestamp of when the suppression was added
+
+	ExpiresAt *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"` // The suppression is lifted at this time; permanent when empty
+	UpdatedAt time.Time  `json:"updated_at" bson:"updated_at"`                     // Timestamp of the last change
+}
+
+func (S Suppression) TableName() string {
+	return "suppressions" // Returns the collection name as 'suppressions'
+}
+
+// NormalizeRecipient returns the form recipients are stored and compared in: the lower case
+// address of an email recipient, or the +E.164 number of a phone recipient (without the
+// "whatsapp:" prefix and separators).
+func NormalizeRecipient(recipient string) string {
+	recipient = strings.TrimSpace(recipient)
+	if strings.Contains(recipient, "@") {
+		if address, err := mail.ParseAddress(recipient); err == nil {
+			recipient = address.Address
+		}
+		return strings.ToLower(recipient)
+	}
+
+	recipient = strings.TrimPrefix(recipient, "whatsapp:")
+	number := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, recipient)
+	return "+" + number
+}
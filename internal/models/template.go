@@ -0,0 +1,53 @@
+/*
+models/template.go
+Author: Akhil C
+Description: This file contains the document model for stored, versioned notification templates.
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Template is one version of a notification template. All versions of a template share
+// the same TemplateID; every update stores a new version.
+type Template struct {
+	ID             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`      // Unique identifier of this version
+	TemplateID     string             `json:"template_id" bson:"template_id"`         // Stable key shared by all versions (e.g. invoice-ready)
+	OrganizationID primitive.ObjectID `json:"organization_id" bson:"organization_id"` // ID of the organization owning the template
+	Name           string             `json:"name" bson:"name"`                       // Human readable name
+	Description    string             `json:"description" bson:"description"`         // Description of the template
+	Version        int                `json:"version" bson:"version"`                 // Version number, starting at 1
+	DefaultLocale  string             `json:"default_locale" bson:"default_locale"`   // Locale used when no variant matches the recipient locale
+	Variants       []TemplateVariant  `json:"variants" bson:"variants"`               // Content per channel
+	Active         bool               `json:"active" bson:"active"`                   // False once the template has been deleted
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`           // Timestamp of when the version was created
+	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`           // Timestamp of when the version was last updated
+}
+
+func (T Template) TableName() string {
+	return "templates" // Returns the collection name as 'templates'
+}
+
+// TemplateVariant holds the content of a template for one channel. Subject and Text are
+// rendered with text/template, HTML with html/template.
+type TemplateVariant struct {
+	Channel string `json:"channel" bson:"channel"`                     // Notification type the variant is used for (email, whatsapp)
+	Locale  string `json:"locale,omitempty" bson:"locale,omitempty"`   // Locale of the content (e.g. pt-BR), empty for the locale independent fallback
+	Subject string `json:"subject,omitempty" bson:"subject,omitempty"` // Subject template (email)
+	HTML    string `json:"html,omitempty" bson:"html,omitempty"`       // HTML body template (email)
+	Text    string `json:"text,omitempty" bson:"text,omitempty"`       // Plain text body template (email plaintext, WhatsApp message)
+}
+
+// Variant returns the variant of the template for the given channel and exact locale
+func (t *Template) Variant(channel, locale string) (*TemplateVariant, bool) {
+	for i := range t.Variants {
+		if t.Variants[i].Channel == channel && t.Variants[i].Locale == locale {
+			return &t.Variants[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,26 @@
+/*
+models/whatsappSession.go
+Author: Akhil C
+Description: This file contains the document model tracking the WhatsApp customer service window of each recipient.
+*/
+
+package models
+
+import (
+	"time"
+)
+
+// WhatsAppSessionWindow is how long free-form messages can be sent after the last message received from a recipient
+const WhatsAppSessionWindow = 24 * time.Hour
+
+// WhatsAppSession records the last inbound message of a recipient. Outside of the session
+// window WhatsApp only accepts approved content templates.
+type WhatsAppSession struct {
+	Recipient     string    `json:"recipient" bson:"_id"`                   // Phone number of the recipient in E.164 format
+	LastInboundAt time.Time `json:"last_inbound_at" bson:"last_inbound_at"` // Timestamp of the last message received from the recipient
+	ExpiresAt     time.Time `json:"expires_at" bson:"expires_at"`           // End of the session window
+}
+
+func (W WhatsAppSession) TableName() string {
+	return "whatsapp_sessions" // Returns the collection name as 'whatsapp_sessions'
+}
@@ -1,48 +1,149 @@
 package notifications
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"net/smtp"
-	"strconv"
 
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/unsubscribe"
+	"github.com/akhilckenshi/notification/pkg/logger"
 	cfg "github.com/akhilckenshi/notification/pkg/settings"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// SendEmail sends an email notification using SMTP or a third-party service.
-func SendEmail(to string, subject string, body string) error {
-	from := cfg.Config.AppEmailID
-	password := cfg.Config.AppEmailPassword
-	smtpHost := cfg.Config.SMTPHost
-	smtpPort := strconv.Itoa(cfg.Config.SMTPPort)
-
-	// Setup the authentication information.
-	auth := smtp.PlainAuth("", from, password, smtpHost)
-
-	// Create the email message.
-	// Notice the "Content-Type" header for HTML.
-	msg := fmt.Sprintf("From: %s\nTo: %s\nSubject: %s\nContent-Type: text/html; charset=UTF-8\n\n%s", from, to, subject, body)
-
-	// Send the email.
-	return smtp.SendMail(smtpHost+":"+smtpPort, auth, from, []string{to}, []byte(msg))
-}
-
-// // SendEmail sends an email notification using SMTP or a third-party service.
-// func SendEmail(to string, subject string, body string) error {
-// 	username := cfg.Config.Email.Username
-// 	from := cfg.Config.Email.Id
-// 	password := cfg.Config.Email.Pwd
-// 	smtpHost := cfg.Config.Email.SmtpHost
-// 	smtpPort := strconv.Itoa(cfg.Config.Email.SmtpPort)
-// 	// Setup the authentication information.
-// 	auth := smtp.PlainAuth("", username, password, smtpHost)
-// 	msg := fmt.Sprintf("From: %s\nTo: %s\nSubject: %s\n\n%s", from, to, subject, body)
-
-// 	err := smtp.SendMail(smtpHost+":"+smtpPort, auth, from, []string{to}, []byte(msg))
-// 	if err != nil {
-// 		fmt.Printf("Failed to send email to %s: %v\n", to, err)
-// 		return err
-// 	}
-
-// 	fmt.Println("Email sent successfully!")
-// 	return nil
-// }
+// SuppressionList reports whether a recipient must not receive notifications of a channel sent
+// on behalf of an organization
+type SuppressionList interface {
+	IsSuppressed(ctx context.Context, orgID primitive.ObjectID, channel, recipient string) (bool, error)
+}
+
+// EmailNotifier delivers notifications of type "email" through a list of providers. Providers
+// are tried in order: when one fails or times out the next one is used.
+type EmailNotifier struct {
+	suppressions SuppressionList
+	providers    []EmailProvider
+}
+
+// NewEmailNotifier creates the email channel sending through providers, in order of preference.
+// Copy recipients on the suppression list, when one is given, are left out of the messages.
+func NewEmailNotifier(suppressions SuppressionList, providers ...EmailProvider) *EmailNotifier {
+	return &EmailNotifier{suppressions: suppressions, providers: providers}
+}
+
+// Send implements Notifier for the email channel. The result names the provider that
+// accepted the message and the identifier it assigned. Suppressed copy recipients are left out
+// of the message; the primary recipient is checked before the notification is dispatched.
+// Marketing emails carry one-click unsubscribe headers (RFC 8058).
+func (e *EmailNotifier) Send(ctx context.Context, notification *models.Notification) (DeliveryResult, error) {
+	msg := &MailMessage{
+		From:        cfg.Config.AppEmailID,
+		To:          []string{notification.To},
+		Cc:          notification.Cc,
+		Bcc:         notification.Bcc,
+		ReplyTo:     notification.ReplyTo,
+		Subject:     notification.Subject,
+		Text:        notification.PlainText,
+		HTML:        notification.Message,
+		Attachments: notification.Attachments,
+		Headers:     unsubscribeHeaders(notification),
+	}
+	if err := e.removeSuppressed(ctx, notification.OrganizationID, msg); err != nil {
+		return DeliveryResult{From: msg.From}, err
+	}
+	return e.sendMail(ctx, msg)
+}
+
+// removeSuppressed drops the copy recipients suppressed for an organization from msg
+func (e *EmailNotifier) removeSuppressed(ctx context.Context, orgID primitive.ObjectID, msg *MailMessage) error {
+	if e.suppressions == nil {
+		return nil
+	}
+	keep := func(addresses []string) ([]string, error) {
+		var kept []string
+		for _, address := range addresses {
+			suppressed, err := e.suppressions.IsSuppressed(ctx, orgID, TypeEmail, address)
+			if err != nil {
+				return nil, err
+			}
+			if !suppressed {
+				kept = append(kept, address)
+			}
+		}
+		return kept, nil
+	}
+	var err error
+	if msg.Cc, err = keep(msg.Cc); err != nil {
+		return err
+	}
+	msg.Bcc, err = keep(msg.Bcc)
+	return err
+}
+
+// unsubscribeHeaders returns the List-Unsubscribe and List-Unsubscribe-Post headers of a marketing
+// email, with a link signed for its recipient. Other emails get no headers.
+func unsubscribeHeaders(notification *models.Notification) map[string]string {
+	if notification.Category != models.CategoryMarketing {
+		return nil
+	}
+	baseURL := firstNonEmpty(cfg.Config.Unsubscribe.BaseURL, cfg.Config.WhatsApp.BaseURL)
+	if cfg.Config.UnsubscribeSecret == "" || baseURL == "" {
+		logger.Log.Warn(fmt.Sprintf("Sending marketing email %s without unsubscribe link: UNSUBSCRIBE_SECRET or unsubscribe.baseUrl is not configured", notification.ID.Hex()))
+		return nil
+	}
+
+	token, err := unsubscribe.Sign([]byte(cfg.Config.UnsubscribeSecret), unsubscribe.Claims{
+		OrganizationID: notification.OrganizationID.Hex(),
+		Recipient:      models.NormalizeRecipient(notification.To),
+		Category:       notification.Category,
+	})
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Failed to sign unsubscribe link of email %s: %v", notification.ID.Hex(), err))
+		return nil
+	}
+	return map[string]string{
+		"List-Unsubscribe":      "<" + unsubscribe.URL(baseURL, token) + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
+
+// sendMail sends msg through the first provider that accepts it
+func (e *EmailNotifier) sendMail(ctx context.Context, msg *MailMessage) (DeliveryResult, error) {
+	result := DeliveryResult{From: msg.From}
+	if len(e.providers) == 0 {
+		return result, Permanent(fmt.Errorf("no email provider configured"))
+	}
+
+	var errs []error
+	for _, provider := range e.providers {
+		messageID, err := provider.Send(ctx, msg)
+		if err == nil {
+			result.Provider = provider.Name()
+			result.ProviderMessageID = messageID
+			return result, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+
+		// Failing over is pointless once the caller gave up
+		if ctx.Err() != nil {
+			break
+		}
+		var permanent *PermanentError
+		if errors.As(err, &permanent) {
+			break // The message itself is invalid, no provider will accept it
+		}
+	}
+	if len(errs) == 1 {
+		return result, errs[0]
+	}
+	return result, &ProvidersFailedError{Errors: errs}
+}
+
+// Close releases the resources (e.g. SMTP connections) held by the providers
+func (e *EmailNotifier) Close() {
+	for _, provider := range e.providers {
+		if closer, ok := provider.(interface{ Close() }); ok {
+			closer.Close()
+		}
+	}
+}
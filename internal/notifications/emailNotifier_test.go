@@ -0,0 +1,194 @@
+/*
+notifications/emailNotifier_test.go
+Author: Akhil C
+Description: Tests of the failover of the email channel across SMTP, SES and SendGrid, against local stand-ins of each provider.
+*/
+
+package notifications
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// providerCalls records the providers that received a message, in order
+type providerCalls struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (c *providerCalls) add(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.names = append(c.names, name)
+}
+
+func (c *providerCalls) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return strings.Join(c.names, ",")
+}
+
+// newSMTPStandIn starts a local SMTP server answering MAIL FROM with mailReply (e.g. "250 OK" or
+// "451 4.3.0 Try again later") and returns its port. Every MAIL FROM is recorded in calls.
+func newSMTPStandIn(t *testing.T, mailReply string, calls *providerCalls) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot start SMTP stand-in: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, mailReply, calls)
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// serveSMTP runs a single SMTP session of the stand-in
+func serveSMTP(conn net.Conn, mailReply string, calls *providerCalls) {
+	defer conn.Close()
+	session := textproto.NewConn(conn)
+	session.PrintfLine("220 localhost ESMTP stand-in")
+	for {
+		line, err := session.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, _, _ := strings.Cut(strings.ToUpper(line), " ")
+		switch verb {
+		case "EHLO", "HELO", "RCPT", "RSET", "NOOP":
+			session.PrintfLine("250 OK")
+		case "MAIL":
+			calls.add(ProviderSMTP)
+			session.PrintfLine("%s", mailReply)
+		case "DATA":
+			session.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			if _, err := session.ReadDotBytes(); err != nil {
+				return
+			}
+			session.PrintfLine("250 OK queued")
+		case "QUIT":
+			session.PrintfLine("221 Bye")
+			return
+		default:
+			session.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// newSMTPStandInProvider returns an SMTP provider sending through an SMTP stand-in
+func newSMTPStandInProvider(t *testing.T, security, mailReply string, calls *providerCalls) *SMTPProvider {
+	port := newSMTPStandIn(t, mailReply, calls)
+	provider := NewSMTPProvider(ProviderSMTP, NewSMTPPool(SMTPPoolConfig{Host: "127.0.0.1", Port: port, Security: security}))
+	t.Cleanup(provider.Close)
+	return provider
+}
+
+// recordingTransport records the HTTP providers called, by host
+type recordingTransport struct {
+	names map[string]string // Provider name by host
+	calls *providerCalls
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.calls.add(rt.names[req.URL.Host])
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestEmailProviderFailover(t *testing.T) {
+	tests := []struct {
+		name           string
+		smtpReply      string // Reply of the SMTP server to MAIL FROM
+		sesStatus      int
+		sendGridStatus int
+		wantCalls      string // Providers tried, in order
+		wantProvider   string // Provider that accepted the message, empty when every provider failed
+		wantRetryable  bool
+	}{
+		{name: "first provider accepts", smtpReply: "250 OK", sesStatus: http.StatusOK, sendGridStatus: http.StatusAccepted, wantCalls: "smtp", wantProvider: "smtp"},
+		{name: "smtp unavailable", smtpReply: "451 4.3.0 Try again later", sesStatus: http.StatusOK, sendGridStatus: http.StatusAccepted, wantCalls: "smtp,ses", wantProvider: "ses"},
+		{name: "smtp rejects", smtpReply: "550 5.7.1 Relaying denied", sesStatus: http.StatusOK, sendGridStatus: http.StatusAccepted, wantCalls: "smtp,ses", wantProvider: "ses"},
+		{name: "smtp and ses unavailable", smtpReply: "451 4.3.0 Try again later", sesStatus: http.StatusServiceUnavailable, sendGridStatus: http.StatusAccepted, wantCalls: "smtp,ses,sendgrid", wantProvider: "sendgrid"},
+		{name: "every provider rejects", smtpReply: "550 5.7.1 Relaying denied", sesStatus: http.StatusBadRequest, sendGridStatus: http.StatusBadRequest, wantCalls: "smtp,ses,sendgrid"},
+		// The message is worth retrying as long as one provider may accept it later
+		{name: "every provider fails, one temporarily", smtpReply: "550 5.7.1 Relaying denied", sesStatus: http.StatusBadRequest, sendGridStatus: http.StatusTooManyRequests, wantCalls: "smtp,ses,sendgrid", wantRetryable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := &providerCalls{}
+			smtpProvider := newSMTPStandInProvider(t, SMTPSecurityNone, tt.smtpReply, calls)
+			sesServer := newSESStandIn(t, "AKIDEXAMPLE", "ses-secret", tt.sesStatus, `{"MessageId":"ses-message"}`, nil)
+			sendGridServer := newSendGridStandIn(t, "sg-key", tt.sendGridStatus, "", nil)
+
+			transport := &recordingTransport{calls: calls, names: map[string]string{
+				strings.TrimPrefix(sesServer.URL, "http://"):      ProviderSES,
+				strings.TrimPrefix(sendGridServer.URL, "http://"): ProviderSendGrid,
+			}}
+			client := &http.Client{Transport: transport}
+			notifier := NewEmailNotifier(nil,
+				smtpProvider,
+				NewSESProvider(ProviderSES, sesServer.URL, "eu-west-1", "AKIDEXAMPLE", "ses-secret", client),
+				NewSendGridProvider(ProviderSendGrid, sendGridServer.URL, "sg-key", client),
+			)
+
+			msg := &MailMessage{From: "billing@example.com", To: []string{"jane@example.com"}, Subject: "Your invoice", Text: "Hello"}
+			result, err := notifier.sendMail(context.Background(), msg)
+			if calls.String() != tt.wantCalls {
+				t.Errorf("providers tried = %s, want %s", calls, tt.wantCalls)
+			}
+
+			if tt.wantProvider != "" {
+				if err != nil {
+					t.Fatalf("sendMail() error = %v", err)
+				}
+				if result.Provider != tt.wantProvider || result.ProviderMessageID == "" {
+					t.Errorf("sent by %q with message ID %q, want %s and its message ID", result.Provider, result.ProviderMessageID, tt.wantProvider)
+				}
+				return
+			}
+			var failed *ProvidersFailedError
+			if !errors.As(err, &failed) || len(failed.Errors) != 3 {
+				t.Fatalf("sendMail() error = %v, want the errors of the three providers", err)
+			}
+			for i, name := range []string{ProviderSMTP, ProviderSES, ProviderSendGrid} {
+				if !strings.HasPrefix(failed.Errors[i].Error(), name+": ") {
+					t.Errorf("error %d = %q, want the error of %s", i, failed.Errors[i], name)
+				}
+			}
+			if IsRetryable(err) != tt.wantRetryable {
+				t.Errorf("IsRetryable(%v) = %v, want %v", err, IsRetryable(err), tt.wantRetryable)
+			}
+		})
+	}
+}
+
+func TestEmailProviderFailoverStopsOnPermanentError(t *testing.T) {
+	calls := &providerCalls{}
+	// The stand-in does not offer STARTTLS, which no attempt can fix
+	smtpProvider := newSMTPStandInProvider(t, SMTPSecurityStartTLS, "250 OK", calls)
+	sesServer := newSESStandIn(t, "AKIDEXAMPLE", "ses-secret", http.StatusOK, `{"MessageId":"ses-message"}`, nil)
+	notifier := NewEmailNotifier(nil, smtpProvider, NewSESProvider(ProviderSES, sesServer.URL, "eu-west-1", "AKIDEXAMPLE", "ses-secret", &http.Client{
+		Transport: &recordingTransport{calls: calls, names: map[string]string{strings.TrimPrefix(sesServer.URL, "http://"): ProviderSES}},
+	}))
+
+	_, err := notifier.sendMail(context.Background(), &MailMessage{From: "billing@example.com", To: []string{"jane@example.com"}, Text: "Hello"})
+	var permanent *PermanentError
+	if !errors.As(err, &permanent) {
+		t.Fatalf("sendMail() error = %v, want a permanent error", err)
+	}
+	if calls.String() != "" {
+		t.Errorf("providers tried = %s, want none after the permanent error of smtp", calls)
+	}
+}
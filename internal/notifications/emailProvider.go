@@ -0,0 +1,153 @@
+/*
+notifications/emailProvider.go
+Author: Akhil C
+Description: Defines the EmailProvider interface implemented by the email backends (SMTP, SES, SendGrid) and builds the providers configured under email.providers.
+*/
+
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	cfg "github.com/akhilckenshi/notification/pkg/settings"
+)
+
+// Email provider types accepted in email.providers
+const (
+	ProviderSMTP     = "smtp"
+	ProviderSES      = "ses"
+	ProviderSendGrid = "sendgrid"
+)
+
+const defaultProviderTimeout = 30 * time.Second
+
+// EmailProvider is implemented by every email backend
+type EmailProvider interface {
+	// Name identifies the provider in logs and on the stored notification
+	Name() string
+	// Send delivers msg and returns the identifier the provider assigned to it
+	Send(ctx context.Context, msg *MailMessage) (string, error)
+}
+
+// HTTPStatusError is returned by HTTP based providers when the API rejects a request
+type HTTPStatusError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("api responded with %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// ProvidersFailedError is returned when every configured provider failed to send an email
+type ProvidersFailedError struct {
+	Errors []error // Error of each provider, in the order they were tried
+}
+
+func (e *ProvidersFailedError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return "all email providers failed: " + strings.Join(messages, "; ")
+}
+
+func (e *ProvidersFailedError) Unwrap() []error {
+	return e.Errors
+}
+
+// SMTPProvider sends emails through a pool of SMTP connections
+type SMTPProvider struct {
+	name string
+	pool *SMTPPool
+}
+
+// NewSMTPProvider creates an SMTP provider sending through pool
+func NewSMTPProvider(name string, pool *SMTPPool) *SMTPProvider {
+	return &SMTPProvider{name: name, pool: pool}
+}
+
+func (p *SMTPProvider) Name() string {
+	return p.name
+}
+
+// Send implements EmailProvider. The Message-ID of the message identifies it.
+func (p *SMTPProvider) Send(ctx context.Context, msg *MailMessage) (string, error) {
+	body, err := msg.Bytes()
+	if err != nil {
+		return "", Permanent(fmt.Errorf("failed to build email: %v", err))
+	}
+	return msg.MessageID, p.pool.Send(ctx, bareAddress(msg.From), msg.Recipients(), body)
+}
+
+// Close closes the connection pool of the provider
+func (p *SMTPProvider) Close() {
+	p.pool.Close()
+}
+
+// NewEmailProvidersFromConfig creates the providers listed under email.providers, ordered by
+// priority (lowest first). Without configured providers a single SMTP provider is created from
+// the email settings. Invalid entries are skipped and reported in the returned error.
+func NewEmailProvidersFromConfig() ([]EmailProvider, error) {
+	configs := append([]cfg.EmailProviderConfig(nil), cfg.Config.Email.Providers...)
+	if len(configs) == 0 {
+		return []EmailProvider{NewSMTPProvider(ProviderSMTP, NewSMTPPoolFromConfig())}, nil
+	}
+	sort.SliceStable(configs, func(i, j int) bool { return configs[i].Priority < configs[j].Priority })
+
+	var providers []EmailProvider
+	var errs []error
+	for _, config := range configs {
+		provider, err := newEmailProvider(config)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers, errors.Join(errs...)
+}
+
+// newEmailProvider creates the provider described by one email.providers entry
+func newEmailProvider(config cfg.EmailProviderConfig) (EmailProvider, error) {
+	name := config.Name
+	if name == "" {
+		name = config.Type
+	}
+	timeout := time.Duration(config.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultProviderTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	switch strings.ToLower(config.Type) {
+	case ProviderSMTP:
+		poolConfig := smtpPoolConfigFromSettings()
+		if config.SmtpHost != "" {
+			poolConfig.Host = config.SmtpHost
+			poolConfig.Port = config.SmtpPort
+			poolConfig.Username = config.Username
+			poolConfig.Password = config.Password
+		}
+		return NewSMTPProvider(name, NewSMTPPool(poolConfig)), nil
+	case ProviderSES:
+		if config.APIKey == "" || config.APISecret == "" || config.Region == "" {
+			return nil, fmt.Errorf("email provider %s: apiKey, apiSecret and region are required", name)
+		}
+		return NewSESProvider(name, config.Endpoint, config.Region, config.APIKey, config.APISecret, client), nil
+	case ProviderSendGrid:
+		if config.APIKey == "" {
+			return nil, fmt.Errorf("email provider %s: apiKey is required", name)
+		}
+		return NewSendGridProvider(name, config.Endpoint, config.APIKey, client), nil
+	default:
+		return nil, fmt.Errorf("email provider %s: unknown type %q", name, config.Type)
+	}
+}
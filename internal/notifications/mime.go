@@ -0,0 +1,279 @@
+/*
+notifications/mime.go
+Author: Akhil C
+Description: Builds RFC 5322 / RFC 2045 compliant email messages with CRLF line endings, multipart/alternative bodies, attachments, inline images and RFC 2047 encoded headers.
+*/
+
+package notifications
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+)
+
+const base64LineLength = 76 // Maximum length of a base64 encoded line (RFC 2045)
+
+// MailMessage describes an email to be encoded by Bytes
+type MailMessage struct {
+	From        string              // Sender address, optionally with display name
+	To          []string            // Primary recipients
+	Cc          []string            // Carbon copy recipients
+	Bcc         []string            // Blind carbon copy recipients, never written to the headers
+	ReplyTo     string              // Address replies should be sent to
+	Subject     string              // Subject, encoded as RFC 2047 when it contains non-ASCII characters
+	Text        string              // Plain text body
+	HTML        string              // HTML body
+	Attachments []models.Attachment // Attachments and inline images (attachments with a ContentID)
+	Headers     map[string]string   // Additional headers
+	MessageID   string              // Message-ID, generated when empty
+	Date        time.Time           // Date header, now when zero
+}
+
+// Recipients returns the envelope recipients (To, Cc and Bcc) as bare addresses
+func (m *MailMessage) Recipients() []string {
+	var recipients []string
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, address := range list {
+			recipients = append(recipients, bareAddress(address))
+		}
+	}
+	return recipients
+}
+
+// Bytes encodes the message. The Message-ID and Date are filled in when missing.
+func (m *MailMessage) Bytes() ([]byte, error) {
+	if m.MessageID == "" {
+		m.MessageID = newMessageID(m.From)
+	}
+	if m.Date.IsZero() {
+		m.Date = time.Now()
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", formatAddressList([]string{m.From}))
+	writeHeader(&buf, "To", formatAddressList(m.To))
+	if len(m.Cc) > 0 {
+		writeHeader(&buf, "Cc", formatAddressList(m.Cc))
+	}
+	if m.ReplyTo != "" {
+		writeHeader(&buf, "Reply-To", formatAddressList([]string{m.ReplyTo}))
+	}
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader(&buf, "Date", m.Date.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", m.MessageID)
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	// Extra headers are written in a stable order
+	names := make([]string, 0, len(m.Headers))
+	for name := range m.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeHeader(&buf, textproto.CanonicalMIMEHeaderKey(name), mime.QEncoding.Encode("utf-8", m.Headers[name]))
+	}
+
+	var inline, attached []models.Attachment
+	for _, attachment := range m.Attachments {
+		if attachment.ContentID != "" {
+			inline = append(inline, attachment)
+		} else {
+			attached = append(attached, attachment)
+		}
+	}
+
+	// multipart/mixed( multipart/related( multipart/alternative(text, html), inline... ), attachments... )
+	// Levels without content are left out.
+	body := func(w io.Writer) (textproto.MIMEHeader, error) { return m.writeAlternative(w) }
+	if len(inline) > 0 {
+		body = wrapMultipart("related", body, inline, "inline")
+	}
+	if len(attached) > 0 {
+		body = wrapMultipart("mixed", body, attached, "attachment")
+	}
+
+	var content bytes.Buffer
+	header, err := body(&content)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+		if value := header.Get(name); value != "" {
+			writeHeader(&buf, name, value)
+		}
+	}
+	buf.WriteString("\r\n")
+	buf.Write(content.Bytes())
+	return buf.Bytes(), nil
+}
+
+// partWriter writes the content of a MIME part to w and returns the headers of the part
+type partWriter func(w io.Writer) (textproto.MIMEHeader, error)
+
+// writeAlternative writes the text and HTML bodies, as multipart/alternative when both are set
+func (m *MailMessage) writeAlternative(w io.Writer) (textproto.MIMEHeader, error) {
+	switch {
+	case m.Text != "" && m.HTML != "":
+		mw := multipart.NewWriter(w)
+		for _, part := range []struct{ contentType, body string }{
+			{"text/plain; charset=UTF-8", m.Text}, // Least preferred alternative first (RFC 2046)
+			{"text/html; charset=UTF-8", m.HTML},
+		} {
+			pw, err := mw.CreatePart(textHeader(part.contentType))
+			if err != nil {
+				return nil, err
+			}
+			if err := writeQuotedPrintable(pw, part.body); err != nil {
+				return nil, err
+			}
+		}
+		if err := mw.Close(); err != nil {
+			return nil, err
+		}
+		return textproto.MIMEHeader{"Content-Type": {"multipart/alternative; boundary=" + mw.Boundary()}}, nil
+	case m.Text != "":
+		return textHeader("text/plain; charset=UTF-8"), writeQuotedPrintable(w, m.Text)
+	default:
+		return textHeader("text/html; charset=UTF-8"), writeQuotedPrintable(w, m.HTML)
+	}
+}
+
+// wrapMultipart returns a partWriter producing a multipart/<subtype> made of the
+// content of inner followed by the given attachments
+func wrapMultipart(subtype string, inner partWriter, attachments []models.Attachment, disposition string) partWriter {
+	return func(w io.Writer) (textproto.MIMEHeader, error) {
+		mw := multipart.NewWriter(w)
+
+		var content bytes.Buffer
+		header, err := inner(&content)
+		if err != nil {
+			return nil, err
+		}
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pw.Write(content.Bytes()); err != nil {
+			return nil, err
+		}
+
+		for _, attachment := range attachments {
+			if err := writeAttachment(mw, attachment, disposition); err != nil {
+				return nil, err
+			}
+		}
+		if err := mw.Close(); err != nil {
+			return nil, err
+		}
+		return textproto.MIMEHeader{"Content-Type": {fmt.Sprintf("multipart/%s; boundary=%s", subtype, mw.Boundary())}}, nil
+	}
+}
+
+// writeAttachment writes a base64 encoded attachment part
+func writeAttachment(mw *multipart.Writer, attachment models.Attachment, disposition string) error {
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(attachment.Filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(contentType, map[string]string{"name": attachment.Filename}))
+	header.Set("Content-Transfer-Encoding", "base64")
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	if attachment.ContentID != "" {
+		header.Set("Content-ID", "<"+strings.Trim(attachment.ContentID, "<>")+">")
+	}
+
+	pw, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+	for len(encoded) > base64LineLength {
+		if _, err := io.WriteString(pw, encoded[:base64LineLength]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[base64LineLength:]
+	}
+	_, err = io.WriteString(pw, encoded+"\r\n")
+	return err
+}
+
+// textHeader returns the headers of a quoted-printable text part
+func textHeader(contentType string) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	}
+}
+
+// writeQuotedPrintable writes body as quoted-printable; line breaks are written as CRLF
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qw, strings.ReplaceAll(body, "\r\n", "\n")); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+// headerBreaks removes line breaks from header names and values, so a value cannot start another
+// header (header injection)
+var headerBreaks = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// writeHeader writes a single header line terminated by CRLF
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(strings.ReplaceAll(headerBreaks.Replace(name), " ", ""))
+	buf.WriteString(": ")
+	buf.WriteString(headerBreaks.Replace(value))
+	buf.WriteString("\r\n")
+}
+
+// formatAddressList formats addresses for an address header, encoding display names as needed
+func formatAddressList(addresses []string) string {
+	formatted := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if parsed, err := mail.ParseAddress(address); err == nil {
+			formatted = append(formatted, parsed.String())
+		} else {
+			formatted = append(formatted, address)
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// bareAddress returns the address part of "Name <address>"
+func bareAddress(address string) string {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		return parsed.Address
+	}
+	return address
+}
+
+// newMessageID generates a unique Message-ID using the domain of the sender
+func newMessageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(bareAddress(from), "@"); at >= 0 {
+		domain = bareAddress(from)[at+1:]
+	}
+	random := make([]byte, 16)
+	rand.Read(random)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain)
+}
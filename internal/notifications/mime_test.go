@@ -0,0 +1,181 @@
+/*
+notifications/mime_test.go
+Author: Akhil C
+Description: Tests of the MIME encoding of emails: header encoding, header injection and body structure.
+*/
+
+package notifications
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/akhilckenshi/notification/internal/models"
+)
+
+// readMailMessage encodes msg and parses the result back
+func readMailMessage(t *testing.T, msg *MailMessage) (*mail.Message, []byte) {
+	t.Helper()
+	raw, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("cannot parse the encoded message: %v\n%s", err, raw)
+	}
+	return parsed, raw
+}
+
+func TestMailMessageHeaders(t *testing.T) {
+	decoder := new(mime.WordDecoder)
+	tests := []struct {
+		name   string
+		msg    MailMessage
+		header string // Header to check, decoded
+		want   string
+	}{
+		{"ASCII subject", MailMessage{Subject: "Your order"}, "Subject", "Your order"},
+		{"non-ASCII subject", MailMessage{Subject: "Ihre Bestellung ist unterwegs – danke!"}, "Subject", "Ihre Bestellung ist unterwegs – danke!"},
+		{"display name", MailMessage{ReplyTo: "Support Team <support@example.com>"}, "Reply-To", `"Support Team" <support@example.com>`},
+		{"non-ASCII display name", MailMessage{ReplyTo: "Zoë <zoe@example.com>"}, "Reply-To", "Zoë <zoe@example.com>"},
+		{"cc", MailMessage{Cc: []string{"a@example.com", "b@example.com"}}, "Cc", "<a@example.com>, <b@example.com>"},
+		{"extra header", MailMessage{Headers: map[string]string{"list-unsubscribe": "<https://example.com/u>"}}, "List-Unsubscribe", "<https://example.com/u>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.msg
+			msg.From = "sender@example.com"
+			msg.To = []string{"recipient@example.com"}
+			msg.Text = "Hello"
+			parsed, _ := readMailMessage(t, &msg)
+
+			got, err := decoder.DecodeHeader(parsed.Header.Get(tt.header))
+			if err != nil {
+				t.Fatalf("cannot decode %s: %v", tt.header, err)
+			}
+			if got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMailMessageHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  MailMessage
+	}{
+		{"subject", MailMessage{To: []string{"recipient@example.com"}, Subject: "Hello\r\nBcc: attacker@example.com"}},
+		{"recipient", MailMessage{To: []string{"recipient@example.com\r\nBcc: attacker@example.com"}}},
+		{"cc", MailMessage{To: []string{"recipient@example.com"}, Cc: []string{"cc@example.com\nBcc: attacker@example.com"}}},
+		{"reply-to", MailMessage{To: []string{"recipient@example.com"}, ReplyTo: "reply@example.com\rBcc: attacker@example.com"}},
+		{"extra header value", MailMessage{To: []string{"recipient@example.com"}, Headers: map[string]string{"X-Campaign": "spring\r\nBcc: attacker@example.com"}}},
+		{"extra header name", MailMessage{To: []string{"recipient@example.com"}, Headers: map[string]string{"X-Campaign\r\nBcc": "attacker@example.com"}}},
+		{"message ID", MailMessage{To: []string{"recipient@example.com"}, MessageID: "<id@example.com>\r\nBcc: attacker@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.msg
+			msg.From = "sender@example.com"
+			msg.Text = "Hello"
+			parsed, raw := readMailMessage(t, &msg)
+
+			if bcc := parsed.Header.Get("Bcc"); bcc != "" {
+				t.Errorf("injected Bcc header %q in\n%s", bcc, raw)
+			}
+			body, _ := io.ReadAll(parsed.Body)
+			if strings.TrimSpace(string(body)) != "Hello" {
+				t.Errorf("body = %q, want the headers to end before it", body)
+			}
+		})
+	}
+}
+
+func TestMailMessageBody(t *testing.T) {
+	attachment := models.Attachment{Filename: "invoice.pdf", Content: bytes.Repeat([]byte("%PDF-1.4 "), 40)}
+	image := models.Attachment{Filename: "logo.png", ContentID: "logo", Content: []byte{0x89, 'P', 'N', 'G'}}
+	tests := []struct {
+		name      string
+		msg       MailMessage
+		mediaType string
+		parts     []string // Media types of the parts of a multipart body
+	}{
+		{"text", MailMessage{Text: "Hello"}, "text/plain", nil},
+		{"html", MailMessage{HTML: "<p>Hello</p>"}, "text/html", nil},
+		{"alternative", MailMessage{Text: "Hello", HTML: "<p>Hello</p>"}, "multipart/alternative", []string{"text/plain", "text/html"}},
+		{"attachment", MailMessage{Text: "Hello", Attachments: []models.Attachment{attachment}}, "multipart/mixed", []string{"text/plain", "application/pdf"}},
+		{"inline image", MailMessage{HTML: `<img src="cid:logo">`, Attachments: []models.Attachment{image}}, "multipart/related", []string{"text/html", "image/png"}},
+		{"inline image and attachment", MailMessage{Text: "Hello", HTML: `<img src="cid:logo">`, Attachments: []models.Attachment{image, attachment}}, "multipart/mixed", []string{"multipart/related", "application/pdf"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.msg
+			msg.From = "sender@example.com"
+			msg.To = []string{"recipient@example.com"}
+			msg.Bcc = []string{"hidden@example.com"}
+			parsed, raw := readMailMessage(t, &msg)
+
+			if bytes.Contains(bytes.ReplaceAll(raw, []byte("\r\n"), nil), []byte("\n")) {
+				t.Errorf("message has line breaks other than CRLF")
+			}
+			if parsed.Header.Get("Bcc") != "" || bytes.Contains(raw, []byte("hidden@example.com")) {
+				t.Errorf("Bcc recipient is written to the message")
+			}
+			mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+			if err != nil || mediaType != tt.mediaType {
+				t.Fatalf("Content-Type = %q (%v), want %s", parsed.Header.Get("Content-Type"), err, tt.mediaType)
+			}
+			if tt.parts == nil {
+				return
+			}
+
+			var parts []string
+			reader := multipart.NewReader(parsed.Body, params["boundary"])
+			for {
+				part, err := reader.NextRawPart()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("cannot read part: %v", err)
+				}
+				partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+				parts = append(parts, partType)
+
+				if part.Header.Get("Content-Transfer-Encoding") == "base64" {
+					content, _ := io.ReadAll(part)
+					for _, line := range strings.Split(strings.TrimSpace(string(content)), "\r\n") {
+						if len(line) > base64LineLength {
+							t.Errorf("base64 line of %d characters, want at most %d", len(line), base64LineLength)
+						}
+					}
+					if _, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(content), "\r\n", "")); err != nil {
+						t.Errorf("invalid base64 content: %v", err)
+					}
+				}
+			}
+			if strings.Join(parts, ",") != strings.Join(tt.parts, ",") {
+				t.Errorf("parts = %v, want %v", parts, tt.parts)
+			}
+		})
+	}
+}
+
+func TestMailMessageRecipients(t *testing.T) {
+	msg := MailMessage{
+		To:  []string{"Jane Doe <jane@example.com>"},
+		Cc:  []string{"cc@example.com"},
+		Bcc: []string{"hidden@example.com"},
+	}
+	want := []string{"jane@example.com", "cc@example.com", "hidden@example.com"}
+	if got := msg.Recipients(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Recipients() = %v, want %v", got, want)
+	}
+}
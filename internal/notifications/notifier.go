@@ -0,0 +1,90 @@
+/*
+notifications/notifier.go
+Author: Akhil C
+Description: Defines the Notifier interface implemented by every delivery channel and the registry used by the service to look up a channel by notification type.
+*/
+
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/akhilckenshi/notification/internal/models"
+)
+
+// Notification types handled by the built-in channels
+const (
+	TypeEmail    = "email"
+	TypeWhatsApp = "whatsapp"
+	TypeSMS      = "sms"
+)
+
+// DeliveryResult describes the outcome reported by a channel after a send attempt
+type DeliveryResult struct {
+	Provider          string // Name of the provider that handled the message (e.g. smtp, twilio)
+	ProviderMessageID string // Identifier assigned to the message by the provider, if any
+	From              string // Sender address or number used for the message
+	Segments          int    // Number of segments the message was billed as (SMS)
+	ProviderStatus    string // Status reported by the provider when the message was submitted
+	ErrorCode         int    // Error code reported by the provider, 0 when none
+	ErrorMessage      string // Error description reported by the provider
+}
+
+// Notifier is implemented by every delivery channel (email, WhatsApp, ...)
+type Notifier interface {
+	Send(ctx context.Context, notification *models.Notification) (DeliveryResult, error)
+}
+
+// UnknownChannelError is returned when no Notifier is registered for a notification type
+type UnknownChannelError struct {
+	Type string
+}
+
+func (e *UnknownChannelError) Error() string {
+	return fmt.Sprintf("unknown notification type: %q", e.Type)
+}
+
+// Registry maps notification types to their Notifier implementation
+type Registry struct {
+	mu        sync.RWMutex
+	notifiers map[string]Notifier
+}
+
+// NewRegistry creates an empty channel registry
+func NewRegistry() *Registry {
+	return &Registry{notifiers: make(map[string]Notifier)}
+}
+
+// Register adds (or replaces) the Notifier used for the given notification type
+func (r *Registry) Register(notificationType string, notifier Notifier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifiers[notificationType] = notifier
+}
+
+// Get returns the Notifier registered for the given notification type.
+// An *UnknownChannelError is returned if the type has not been registered.
+func (r *Registry) Get(notificationType string) (Notifier, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	notifier, ok := r.notifiers[notificationType]
+	if !ok {
+		return nil, &UnknownChannelError{Type: notificationType}
+	}
+	return notifier, nil
+}
+
+// Types returns the registered notification types in sorted order
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]string, 0, len(r.notifiers))
+	for notificationType := range r.notifiers {
+		types = append(types, notificationType)
+	}
+	sort.Strings(types)
+	return types
+}
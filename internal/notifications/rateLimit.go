@@ -0,0 +1,63 @@
+/*
+notifications/rateLimit.go
+Author: Akhil C
+Description: Applies the rate limits of providers to the email backends and to the channels sending through a single provider.
+*/
+
+package notifications
+
+import (
+	"context"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/ratelimit"
+)
+
+// rateLimitedProvider sends through an email provider while the rate limit of the provider allows it
+type rateLimitedProvider struct {
+	EmailProvider
+	limiter *ratelimit.Limiter
+}
+
+// LimitEmailProvider applies the rate limit configured for the provider. A provider over its limit
+// returns a *ratelimit.LimitedError, so the email notifier fails over to the next provider.
+func LimitEmailProvider(provider EmailProvider, limiter *ratelimit.Limiter) EmailProvider {
+	return &rateLimitedProvider{EmailProvider: provider, limiter: limiter}
+}
+
+// Send implements EmailProvider
+func (p *rateLimitedProvider) Send(ctx context.Context, msg *MailMessage) (string, error) {
+	if err := p.limiter.Take(ratelimit.Key{Scope: ratelimit.ScopeProvider, Name: p.Name()}); err != nil {
+		return "", err
+	}
+	return p.EmailProvider.Send(ctx, msg)
+}
+
+// Close closes the wrapped provider when it holds resources
+func (p *rateLimitedProvider) Close() {
+	if closer, ok := p.EmailProvider.(interface{ Close() }); ok {
+		closer.Close()
+	}
+}
+
+// rateLimitedNotifier sends through a channel while the rate limit of its provider allows it
+type rateLimitedNotifier struct {
+	Notifier
+	provider string
+	limiter  *ratelimit.Limiter
+}
+
+// LimitNotifier applies the rate limit configured for provider to a channel sending every
+// message through it (e.g. twilio for SMS and WhatsApp). Channels sharing a provider share its
+// limit. A *ratelimit.LimitedError is returned when the limit is reached.
+func LimitNotifier(notifier Notifier, provider string, limiter *ratelimit.Limiter) Notifier {
+	return &rateLimitedNotifier{Notifier: notifier, provider: provider, limiter: limiter}
+}
+
+// Send implements Notifier
+func (n *rateLimitedNotifier) Send(ctx context.Context, notification *models.Notification) (DeliveryResult, error) {
+	if err := n.limiter.Take(ratelimit.Key{Scope: ratelimit.ScopeProvider, Name: n.provider}); err != nil {
+		return DeliveryResult{Provider: n.provider}, err
+	}
+	return n.Notifier.Send(ctx, notification)
+}
@@ -0,0 +1,168 @@
+/*
+notifications/retry.go
+Author: Akhil C
+Description: Per-channel retry policies with exponential backoff and classification of send errors into retryable and permanent failures.
+*/
+
+package notifications
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net/textproto"
+	"time"
+
+	cfg "github.com/akhilckenshi/notification/pkg/settings"
+
+	twclient "github.com/twilio/twilio-go/client"
+)
+
+// Defaults applied when a channel has no retry configuration
+const (
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = 2 * time.Second
+	defaultMaxDelay    = 1 * time.Minute
+	defaultJitter      = 0.2
+)
+
+// Twilio error codes that indicate a temporary condition on Twilio's side
+var retryableTwilioCodes = map[int]bool{
+	20429: true, // Too many requests
+	20500: true, // Internal server error
+	20503: true, // Service unavailable
+	30001: true, // Queue overflow
+	30009: true, // Missing segment
+}
+
+// RetryPolicy controls how often and how fast a failed send is retried
+type RetryPolicy struct {
+	MaxAttempts int           // Total number of attempts, including the first one
+	BaseDelay   time.Duration // Delay before the first retry, doubled on every following retry
+	MaxDelay    time.Duration // Upper bound of the delay between two attempts
+	Jitter      float64       // Random fraction (0-1) added to or removed from each delay
+}
+
+// RetryPolicyFor returns the retry policy configured for a notification type,
+// falling back to the defaults for values that are not set.
+func RetryPolicyFor(notificationType string) RetryPolicy {
+	policy := RetryPolicy{
+		MaxAttempts: defaultMaxAttempts,
+		BaseDelay:   defaultBaseDelay,
+		MaxDelay:    defaultMaxDelay,
+		Jitter:      defaultJitter,
+	}
+
+	conf, ok := cfg.Config.Retry[notificationType]
+	if !ok {
+		return policy
+	}
+	if conf.MaxAttempts > 0 {
+		policy.MaxAttempts = conf.MaxAttempts
+	}
+	if conf.BaseDelayMs > 0 {
+		policy.BaseDelay = time.Duration(conf.BaseDelayMs) * time.Millisecond
+	}
+	if conf.MaxDelayMs > 0 {
+		policy.MaxDelay = time.Duration(conf.MaxDelayMs) * time.Millisecond
+	}
+	if conf.Jitter > 0 && conf.Jitter <= 1 {
+		policy.Jitter = conf.Jitter
+	}
+	return policy
+}
+
+// Backoff returns the delay to wait after the given failed attempt (starting at 1)
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		// Spread the delay uniformly over [delay*(1-jitter), delay*(1+jitter)]
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// PermanentError wraps a send error that must not be retried
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marks err as not retryable
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsRetryable reports whether a failed send may succeed when attempted again.
+// SMTP 4xx replies and temporary Twilio failures are retryable, SMTP 5xx replies,
+// Twilio client errors and errors marked with Permanent are not. Other errors
+// (network failures, timeouts) are treated as transient.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var permanent *PermanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+
+	var unknown *UnknownChannelError
+	if errors.As(err, &unknown) {
+		return false
+	}
+
+	// Checked before the errors of single providers, which errors.As would find among the
+	// errors of every provider
+	var providersErr *ProvidersFailedError
+	if errors.As(err, &providersErr) {
+		// Worth retrying as long as one of the providers may accept the message later
+		for _, providerErr := range providersErr.Errors {
+			if IsRetryable(providerErr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code >= 400 && smtpErr.Code < 500
+	}
+
+	var httpErr *HTTPStatusError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == 408 || httpErr.StatusCode == 429 || httpErr.StatusCode >= 500
+	}
+
+	var twilioErr *twclient.TwilioRestError
+	if errors.As(err, &twilioErr) {
+		if retryableTwilioCodes[twilioErr.Code] {
+			return true
+		}
+		return twilioErr.Status == 429 || twilioErr.Status >= 500
+	}
+
+	var messageErr *TwilioMessageError
+	if errors.As(err, &messageErr) {
+		return retryableTwilioCodes[messageErr.Code]
+	}
+
+	return true
+}
@@ -0,0 +1,162 @@
+/*
+notifications/sendgridProvider.go
+Author: Akhil C
+Description: Email provider sending messages through the SendGrid v3 Mail Send API.
+*/
+
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sendGridEndpoint = "https://api.sendgrid.com"
+	sendGridSendPath = "/v3/mail/send"
+)
+
+// SendGridProvider sends emails through the SendGrid v3 API
+type SendGridProvider struct {
+	name     string
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewSendGridProvider creates a SendGrid provider. The endpoint defaults to the SendGrid API;
+// any compatible HTTP stand-in can be used instead.
+func NewSendGridProvider(name, endpoint, apiKey string, client *http.Client) *SendGridProvider {
+	if endpoint == "" {
+		endpoint = sendGridEndpoint
+	}
+	return &SendGridProvider{name: name, endpoint: strings.TrimRight(endpoint, "/"), apiKey: apiKey, client: client}
+}
+
+func (p *SendGridProvider) Name() string {
+	return p.name
+}
+
+// sendGridAddress is an email address with an optional display name
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To  []sendGridAddress `json:"to"`
+	Cc  []sendGridAddress `json:"cc,omitempty"`
+	Bcc []sendGridAddress `json:"bcc,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content     []byte `json:"content"` // Base64 encoded in JSON
+	Type        string `json:"type,omitempty"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition,omitempty"`
+	ContentID   string `json:"content_id,omitempty"`
+}
+
+// sendGridRequest is the body of a Mail Send request
+type sendGridRequest struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	ReplyTo          *sendGridAddress          `json:"reply_to,omitempty"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+}
+
+// Send implements EmailProvider. SendGrid returns the message ID in the X-Message-Id header.
+func (p *SendGridProvider) Send(ctx context.Context, msg *MailMessage) (string, error) {
+	request := sendGridRequest{
+		Personalizations: []sendGridPersonalization{{
+			To:  sendGridAddresses(msg.To),
+			Cc:  sendGridAddresses(msg.Cc),
+			Bcc: sendGridAddresses(msg.Bcc),
+		}},
+		From:    sendGridAddressOf(msg.From),
+		Subject: msg.Subject,
+		Headers: msg.Headers,
+	}
+	if msg.ReplyTo != "" {
+		replyTo := sendGridAddressOf(msg.ReplyTo)
+		request.ReplyTo = &replyTo
+	}
+	// text/plain must precede text/html
+	if msg.Text != "" {
+		request.Content = append(request.Content, sendGridContent{Type: "text/plain", Value: msg.Text})
+	}
+	if msg.HTML != "" {
+		request.Content = append(request.Content, sendGridContent{Type: "text/html", Value: msg.HTML})
+	}
+	for _, attachment := range msg.Attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(attachment.Filename))
+		}
+		disposition := "attachment"
+		if attachment.ContentID != "" {
+			disposition = "inline"
+		}
+		request.Attachments = append(request.Attachments, sendGridAttachment{
+			Content:     attachment.Content,
+			Type:        contentType,
+			Filename:    attachment.Filename,
+			Disposition: disposition,
+			ContentID:   strings.Trim(attachment.ContentID, "<>"),
+		})
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", Permanent(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+sendGridSendPath, bytes.NewReader(body))
+	if err != nil {
+		return "", Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return "", &HTTPStatusError{Provider: p.name, StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return resp.Header.Get("X-Message-Id"), nil
+}
+
+// sendGridAddresses converts "Name <address>" strings to SendGrid addresses
+func sendGridAddresses(addresses []string) []sendGridAddress {
+	var result []sendGridAddress
+	for _, address := range addresses {
+		result = append(result, sendGridAddressOf(address))
+	}
+	return result
+}
+
+// sendGridAddressOf converts a "Name <address>" string to a SendGrid address
+func sendGridAddressOf(address string) sendGridAddress {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		return sendGridAddress{Email: parsed.Address, Name: parsed.Name}
+	}
+	return sendGridAddress{Email: address}
+}
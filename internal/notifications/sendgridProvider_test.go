@@ -0,0 +1,146 @@
+/*
+notifications/sendgridProvider_test.go
+Author: Akhil C
+Description: Tests of the SendGrid provider against a local HTTP stand-in: request payload and mapping of API errors.
+*/
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/akhilckenshi/notification/internal/models"
+)
+
+// newSendGridStandIn starts a SendGrid stand-in accepting requests authenticated with apiKey. They
+// are answered with status and body, and the message ID of accepted requests; the last request
+// is stored in received.
+func newSendGridStandIn(t *testing.T, apiKey string, status int, body string, received *sendGridRequest) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != sendGridSendPath || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+apiKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"errors":[{"message":"The provided authorization grant is invalid, expired, or revoked"}]}`)
+			return
+		}
+		if received != nil {
+			if err := json.NewDecoder(r.Body).Decode(received); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if status/100 == 2 {
+			w.Header().Set("X-Message-Id", "sendgrid-message")
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSendGridProviderRequest(t *testing.T) {
+	var received sendGridRequest
+	server := newSendGridStandIn(t, "sg-key", http.StatusAccepted, "", &received)
+	provider := NewSendGridProvider("sendgrid", server.URL, "sg-key", server.Client())
+
+	msg := &MailMessage{
+		From:    "Billing <billing@example.com>",
+		To:      []string{"Jane Doe <jane@example.com>"},
+		Cc:      []string{"cc@example.com"},
+		Bcc:     []string{"hidden@example.com"},
+		ReplyTo: "support@example.com",
+		Subject: "Your invoice",
+		Text:    "Hello",
+		HTML:    `<p>Hello</p><img src="cid:logo">`,
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/u>"},
+		Attachments: []models.Attachment{
+			{Filename: "invoice.pdf", Content: []byte("%PDF-1.4")},
+			{Filename: "logo.png", ContentID: "<logo>", Content: []byte{0x89, 'P', 'N', 'G'}},
+		},
+	}
+	messageID, err := provider.Send(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if messageID != "sendgrid-message" {
+		t.Errorf("Send() = %q, want the X-Message-Id of the response", messageID)
+	}
+
+	want := sendGridRequest{
+		Personalizations: []sendGridPersonalization{{
+			To:  []sendGridAddress{{Email: "jane@example.com", Name: "Jane Doe"}},
+			Cc:  []sendGridAddress{{Email: "cc@example.com"}},
+			Bcc: []sendGridAddress{{Email: "hidden@example.com"}},
+		}},
+		From:    sendGridAddress{Email: "billing@example.com", Name: "Billing"},
+		ReplyTo: &sendGridAddress{Email: "support@example.com"},
+		Subject: "Your invoice",
+		// text/plain must precede text/html
+		Content: []sendGridContent{{Type: "text/plain", Value: "Hello"}, {Type: "text/html", Value: `<p>Hello</p><img src="cid:logo">`}},
+		Attachments: []sendGridAttachment{
+			{Content: []byte("%PDF-1.4"), Type: "application/pdf", Filename: "invoice.pdf", Disposition: "attachment"},
+			{Content: []byte{0x89, 'P', 'N', 'G'}, Type: "image/png", Filename: "logo.png", Disposition: "inline", ContentID: "logo"},
+		},
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/u>"},
+	}
+	if !reflect.DeepEqual(received, want) {
+		got, _ := json.MarshalIndent(received, "", "  ")
+		wanted, _ := json.MarshalIndent(want, "", "  ")
+		t.Errorf("request =\n%s\nwant\n%s", got, wanted)
+	}
+}
+
+func TestSendGridProviderErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		apiKey        string // API key of the provider; the stand-in expects "sg-key"
+		status        int
+		body          string
+		wantRetryable bool
+	}{
+		{name: "invalid API key", apiKey: "revoked-key", status: http.StatusAccepted, body: ""},
+		{name: "bad request", apiKey: "sg-key", status: http.StatusBadRequest, body: `{"errors":[{"message":"Does not contain a valid address.","field":"personalizations.0.to.0.email"}]}`},
+		{name: "payload too large", apiKey: "sg-key", status: http.StatusRequestEntityTooLarge, body: `{"errors":[{"message":"too large"}]}`},
+		{name: "rate limited", apiKey: "sg-key", status: http.StatusTooManyRequests, body: `{"errors":[{"message":"too many requests"}]}`, wantRetryable: true},
+		{name: "server error", apiKey: "sg-key", status: http.StatusInternalServerError, wantRetryable: true},
+		{name: "unavailable", apiKey: "sg-key", status: http.StatusServiceUnavailable, wantRetryable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSendGridStandIn(t, "sg-key", tt.status, tt.body, nil)
+			provider := NewSendGridProvider("sendgrid", server.URL, tt.apiKey, server.Client())
+
+			_, err := provider.Send(context.Background(), &MailMessage{From: "billing@example.com", To: []string{"jane@example.com"}, Text: "Hello"})
+			var statusErr *HTTPStatusError
+			if !errors.As(err, &statusErr) || statusErr.Provider != "sendgrid" {
+				t.Fatalf("Send() error = %v, want an HTTPStatusError of sendgrid", err)
+			}
+			wantStatus := tt.status
+			if tt.apiKey != "sg-key" {
+				wantStatus = http.StatusUnauthorized
+			}
+			if statusErr.StatusCode != wantStatus {
+				t.Errorf("status = %d, want %d", statusErr.StatusCode, wantStatus)
+			}
+			// The response body explains the rejection
+			if tt.body != "" && !strings.Contains(err.Error(), tt.body) {
+				t.Errorf("Send() error = %q, want the response body", err)
+			}
+			if IsRetryable(err) != tt.wantRetryable {
+				t.Errorf("IsRetryable(%v) = %v, want %v", err, IsRetryable(err), tt.wantRetryable)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+/*
+notifications/sesProvider.go
+Author: Akhil C
+Description: Email provider sending raw MIME messages through the Amazon SES v2 SendEmail API, signed with AWS Signature Version 4.
+*/
+
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const sesSendPath = "/v2/email/outbound-emails"
+
+// SESProvider sends emails through the SES v2 API
+type SESProvider struct {
+	name      string
+	endpoint  string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// NewSESProvider creates an SES provider. The endpoint defaults to the regional SES endpoint;
+// any compatible HTTP stand-in can be used instead.
+func NewSESProvider(name, endpoint, region, accessKey, secretKey string, client *http.Client) *SESProvider {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://email.%s.amazonaws.com", region)
+	}
+	return &SESProvider{
+		name:      name,
+		endpoint:  strings.TrimRight(endpoint, "/"),
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    client,
+	}
+}
+
+func (p *SESProvider) Name() string {
+	return p.name
+}
+
+// sesSendRequest is the body of a SendEmail request with raw content
+type sesSendRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses  []string `json:"ToAddresses,omitempty"`
+		CcAddresses  []string `json:"CcAddresses,omitempty"`
+		BccAddresses []string `json:"BccAddresses,omitempty"`
+	} `json:"Destination"`
+	Content struct {
+		Raw struct {
+			Data []byte `json:"Data"` // Raw MIME message, base64 encoded in JSON
+		} `json:"Raw"`
+	} `json:"Content"`
+}
+
+// Send implements EmailProvider
+func (p *SESProvider) Send(ctx context.Context, msg *MailMessage) (string, error) {
+	raw, err := msg.Bytes()
+	if err != nil {
+		return "", Permanent(fmt.Errorf("failed to build email: %v", err))
+	}
+
+	var request sesSendRequest
+	request.FromEmailAddress = msg.From
+	request.Destination.ToAddresses = msg.To
+	request.Destination.CcAddresses = msg.Cc
+	request.Destination.BccAddresses = msg.Bcc
+	request.Content.Raw.Data = raw
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+sesSendPath, bytes.NewReader(body))
+	if err != nil {
+		return "", Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	p.sign(req, body, time.Now().UTC())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return "", &HTTPStatusError{Provider: p.name, StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	var response struct {
+		MessageId string `json:"MessageId"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return "", fmt.Errorf("%s: invalid response: %v", p.name, err)
+	}
+	return response.MessageId, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req
+func (p *SESProvider) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := date + "/" + p.region + "/ses/aws4_request"
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "content-type:" + req.Header.Get("Content-Type") + "\n" +
+		"host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+p.secretKey), date)
+	key = hmacSHA256(key, p.region)
+	key = hmacSHA256(key, "ses")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", p.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,177 @@
+/*
+notifications/sesProvider_test.go
+Author: Akhil C
+Description: Tests of the SES provider against a local HTTP stand-in verifying the request and its AWS Signature Version 4.
+*/
+
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// sesAuthorization matches the Authorization header of a request signed with AWS Signature Version 4
+var sesAuthorization = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/(\d{8})/([^/]+)/ses/aws4_request, SignedHeaders=([a-z0-9;-]+), Signature=([0-9a-f]{64})$`)
+
+// verifySigV4 checks the signature of a request received by the SES stand-in the way SES does:
+// the signature is computed again from the received request and the secret key of the caller
+func verifySigV4(r *http.Request, body []byte, accessKey, secretKey string) error {
+	match := sesAuthorization.FindStringSubmatch(r.Header.Get("Authorization"))
+	if match == nil {
+		return fmt.Errorf("malformed Authorization header %q", r.Header.Get("Authorization"))
+	}
+	credential, date, region, signedHeaders, signature := match[1], match[2], match[3], match[4], match[5]
+	if credential != accessKey {
+		return fmt.Errorf("unknown access key %s", credential)
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(amzDate, date+"T") {
+		return fmt.Errorf("X-Amz-Date %q does not match the credential date %s", amzDate, date)
+	}
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	if r.Header.Get("X-Amz-Content-Sha256") != payloadHash {
+		return errors.New("X-Amz-Content-Sha256 does not match the body")
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{r.Method, r.URL.EscapedPath(), r.URL.RawQuery, canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, date + "/" + region + "/ses/aws4_request", hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + secretKey)
+	for _, data := range []string{date, region, "ses", "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(data))
+		key = mac.Sum(nil)
+	}
+	if !hmac.Equal([]byte(signature), []byte(hex.EncodeToString(key))) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// newSESStandIn starts an SES stand-in accepting requests signed with accessKey and secretKey.
+// Signed requests are answered with status and body; the last one is stored in received.
+func newSESStandIn(t *testing.T, accessKey, secretKey string, status int, body string, received *sesSendRequest) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestBody, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.URL.Path != sesSendPath || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, fmt.Sprintf("unexpected %s %s (%s)", r.Method, r.URL.Path, r.Header.Get("Content-Type")), http.StatusNotFound)
+			return
+		}
+		if err := verifySigV4(r, requestBody, accessKey, secretKey); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, `{"message":"SignatureDoesNotMatch: %s"}`, err)
+			return
+		}
+		if received != nil {
+			if err := json.Unmarshal(requestBody, received); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSESProviderRequest(t *testing.T) {
+	var received sesSendRequest
+	server := newSESStandIn(t, "AKIDEXAMPLE", "ses-secret", http.StatusOK, `{"MessageId":"0100018f-ses-message"}`, &received)
+	provider := NewSESProvider("ses", server.URL+"/", "eu-west-1", "AKIDEXAMPLE", "ses-secret", server.Client())
+
+	msg := &MailMessage{
+		From:    "Billing <billing@example.com>",
+		To:      []string{"jane@example.com"},
+		Cc:      []string{"cc@example.com"},
+		Bcc:     []string{"hidden@example.com"},
+		Subject: "Ihre Rechnung",
+		Text:    "Hello",
+	}
+	messageID, err := provider.Send(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if messageID != "0100018f-ses-message" {
+		t.Errorf("Send() = %q, want the MessageId of the response", messageID)
+	}
+
+	if received.FromEmailAddress != msg.From {
+		t.Errorf("FromEmailAddress = %q, want %q", received.FromEmailAddress, msg.From)
+	}
+	destination := received.Destination
+	if strings.Join(destination.ToAddresses, ",") != "jane@example.com" || strings.Join(destination.CcAddresses, ",") != "cc@example.com" || strings.Join(destination.BccAddresses, ",") != "hidden@example.com" {
+		t.Errorf("Destination = %+v, want the To, Cc and Bcc recipients", destination)
+	}
+	// The raw content is the MIME message, without the Bcc recipients
+	raw, err := mail.ReadMessage(bytes.NewReader(received.Content.Raw.Data))
+	if err != nil {
+		t.Fatalf("invalid raw content: %v", err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(raw.Header.Get("Subject")); subject != msg.Subject {
+		t.Errorf("Subject = %q, want %q", subject, msg.Subject)
+	}
+	if bytes.Contains(received.Content.Raw.Data, []byte("hidden@example.com")) {
+		t.Errorf("raw content names the Bcc recipient")
+	}
+}
+
+func TestSESProviderErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		secretKey     string // Secret key the provider signs with; the stand-in expects "ses-secret"
+		status        int
+		body          string
+		wantStatus    int // Status of the HTTPStatusError, 0 when another error is expected
+		wantRetryable bool
+	}{
+		{name: "signed with another secret", secretKey: "other-secret", status: http.StatusOK, body: `{"MessageId":"id"}`, wantStatus: http.StatusForbidden},
+		{name: "rejected message", secretKey: "ses-secret", status: http.StatusBadRequest, body: `{"message":"Illegal address"}`, wantStatus: http.StatusBadRequest},
+		{name: "throttled", secretKey: "ses-secret", status: http.StatusTooManyRequests, body: `{"message":"Maximum sending rate exceeded"}`, wantStatus: http.StatusTooManyRequests, wantRetryable: true},
+		{name: "unavailable", secretKey: "ses-secret", status: http.StatusServiceUnavailable, wantStatus: http.StatusServiceUnavailable, wantRetryable: true},
+		{name: "invalid response", secretKey: "ses-secret", status: http.StatusOK, body: "<html>", wantRetryable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSESStandIn(t, "AKIDEXAMPLE", "ses-secret", tt.status, tt.body, nil)
+			provider := NewSESProvider("ses", server.URL, "eu-west-1", "AKIDEXAMPLE", tt.secretKey, server.Client())
+
+			_, err := provider.Send(context.Background(), &MailMessage{From: "billing@example.com", To: []string{"jane@example.com"}, Text: "Hello"})
+			if err == nil {
+				t.Fatal("Send() succeeded, want an error")
+			}
+			var statusErr *HTTPStatusError
+			if tt.wantStatus != 0 && (!errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus || statusErr.Provider != "ses") {
+				t.Errorf("Send() error = %v, want an HTTPStatusError %d of ses", err, tt.wantStatus)
+			}
+			if IsRetryable(err) != tt.wantRetryable {
+				t.Errorf("IsRetryable(%v) = %v, want %v", err, IsRetryable(err), tt.wantRetryable)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+notifications/smsNotifier.go
+Author: Akhil C
+Description: SMS channel sending text messages through Twilio, with segment counting and handling of messages longer than the configured maximum.
+*/
+
+package notifications
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	cfg "github.com/akhilckenshi/notification/pkg/settings"
+
+	openapi "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// defaultMaxSMSSegments is the longest message sent when sms.maxSegments is not configured
+const defaultMaxSMSSegments = 10
+
+// MessageTooLongError is returned when an SMS needs more segments than allowed
+type MessageTooLongError struct {
+	Segments    int
+	MaxSegments int
+}
+
+func (e *MessageTooLongError) Error() string {
+	return fmt.Sprintf("message needs %d SMS segments, at most %d are allowed", e.Segments, e.MaxSegments)
+}
+
+// SMSNotifier delivers notifications of type "sms" through Twilio
+type SMSNotifier struct{}
+
+// NewSMSNotifier creates the SMS channel
+func NewSMSNotifier() *SMSNotifier {
+	return &SMSNotifier{}
+}
+
+// Send implements Notifier for the SMS channel. The result reports the Twilio message SID
+// and the number of segments the message was sent as.
+func (s *SMSNotifier) Send(ctx context.Context, notification *models.Notification) (DeliveryResult, error) {
+	result := DeliveryResult{Provider: "twilio", From: cfg.Config.SMSFromNumber}
+
+	body, err := SMSBody(notification)
+	if err != nil {
+		return result, Permanent(err)
+	}
+	result.Segments = CountSMSSegments(body).Segments
+
+	params := &openapi.CreateMessageParams{}
+	params.SetTo(notification.To)
+	params.SetBody(body)
+	// A messaging service picks the sender from its number pool
+	if sid := cfg.Config.SMSMessagingServiceSID; sid != "" {
+		params.SetMessagingServiceSid(sid)
+		result.From = sid
+	} else {
+		params.SetFrom(cfg.Config.SMSFromNumber)
+	}
+
+	twilioResult, err := createTwilioMessage(params)
+	return twilioResult.deliveryResult(result), err
+}
+
+// SMSBody returns the text sent for a notification: the plain text when present, the message
+// otherwise, prefixed with the subject. Texts longer than sms.maxSegments are truncated when
+// sms.truncateLongMessages is set and rejected with a *MessageTooLongError otherwise.
+func SMSBody(notification *models.Notification) (string, error) {
+	body := notification.Message
+	if notification.PlainText != "" {
+		body = notification.PlainText
+	}
+	if notification.Subject != "" {
+		body = fmt.Sprintf("%s: %s", notification.Subject, body)
+	}
+
+	maxSegments := cfg.Config.SMS.MaxSegments
+	if maxSegments <= 0 {
+		maxSegments = defaultMaxSMSSegments
+	}
+	if segments := CountSMSSegments(body).Segments; segments > maxSegments {
+		if !cfg.Config.SMS.TruncateLongMessages {
+			return "", &MessageTooLongError{Segments: segments, MaxSegments: maxSegments}
+		}
+		body = TruncateSMS(body, maxSegments)
+	}
+	return body, nil
+}
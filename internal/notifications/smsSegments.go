@@ -0,0 +1,129 @@
+/*
+notifications/smsSegments.go
+Author: Akhil C
+Description: SMS encoding detection (GSM-7 or UCS-2) and segment counting for concatenated messages.
+*/
+
+package notifications
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SMS encodings
+const (
+	EncodingGSM7 = "GSM-7"
+	EncodingUCS2 = "UCS-2"
+)
+
+// Characters per segment of a single and of a concatenated message. Concatenated messages
+// lose room to the user data header that links the segments together.
+const (
+	gsm7SingleSegment = 160
+	gsm7MultiSegment  = 153
+	ucs2SingleSegment = 70
+	ucs2MultiSegment  = 67
+)
+
+// gsm7Basic is the GSM 03.38 basic character set; each character takes one septet
+const gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsm7Extended is the GSM 03.38 extension table; each character takes two septets (escape + character)
+const gsm7Extended = "\f^{}\\[~]|€"
+
+// SMSSegments describes how a text is split when sent as an SMS
+type SMSSegments struct {
+	Encoding string // EncodingGSM7 or EncodingUCS2
+	Units    int    // Length in septets (GSM-7) or UTF-16 code units (UCS-2)
+	Segments int    // Number of segments the message is billed as
+}
+
+// CountSMSSegments returns the encoding and number of segments needed to send text.
+// Escape sequences and surrogate pairs are never split across segments.
+func CountSMSSegments(text string) SMSSegments {
+	encoding := smsEncoding(text)
+	single, multi := gsm7SingleSegment, gsm7MultiSegment
+	if encoding == EncodingUCS2 {
+		single, multi = ucs2SingleSegment, ucs2MultiSegment
+	}
+
+	result := SMSSegments{Encoding: encoding}
+	for _, r := range text {
+		result.Units += smsUnits(r, encoding)
+	}
+	switch {
+	case result.Units == 0:
+		result.Segments = 0
+	case result.Units <= single:
+		result.Segments = 1
+	default:
+		result.Segments = len(splitSMS(text, encoding, multi))
+	}
+	return result
+}
+
+// TruncateSMS shortens text so that it fits in maxSegments, ending it with an ellipsis
+func TruncateSMS(text string, maxSegments int) string {
+	if maxSegments <= 0 || CountSMSSegments(text).Segments <= maxSegments {
+		return text
+	}
+
+	// Drop characters from the end until the text and the ellipsis fit
+	const ellipsis = "..."
+	runes := []rune(text)
+	for len(runes) > 0 {
+		candidate := string(runes) + ellipsis
+		if CountSMSSegments(candidate).Segments <= maxSegments {
+			return candidate
+		}
+		runes = runes[:len(runes)-1]
+	}
+	return ellipsis
+}
+
+// smsEncoding returns EncodingGSM7 when every character of text is in the GSM 03.38 alphabet
+func smsEncoding(text string) string {
+	for _, r := range text {
+		if !strings.ContainsRune(gsm7Basic, r) && !strings.ContainsRune(gsm7Extended, r) {
+			return EncodingUCS2
+		}
+	}
+	return EncodingGSM7
+}
+
+// smsUnits returns the number of septets or UTF-16 code units taken by r
+func smsUnits(r rune, encoding string) int {
+	if encoding == EncodingGSM7 {
+		if strings.ContainsRune(gsm7Extended, r) {
+			return 2
+		}
+		return 1
+	}
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2 // Surrogate pair
+	}
+	return 1
+}
+
+// splitSMS splits text into segments of at most size units without splitting a character
+func splitSMS(text, encoding string, size int) []string {
+	var segments []string
+	var current strings.Builder
+	units := 0
+	for _, r := range text {
+		n := smsUnits(r, encoding)
+		if units+n > size {
+			segments = append(segments, current.String())
+			current.Reset()
+			units = 0
+		}
+		current.WriteRune(r)
+		units += n
+	}
+	if current.Len() > 0 {
+		segments = append(segments, current.String())
+	}
+	return segments
+}
@@ -0,0 +1,72 @@
+/*
+notifications/smsSegments_test.go
+Author: Akhil C
+Description: Tests of SMS encoding detection, segment counting and truncation.
+*/
+
+package notifications
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountSMSSegments(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want SMSSegments
+	}{
+		{"empty", "", SMSSegments{Encoding: EncodingGSM7, Units: 0, Segments: 0}},
+		{"short GSM-7", "Hello", SMSSegments{Encoding: EncodingGSM7, Units: 5, Segments: 1}},
+		{"GSM-7 accents of the basic set", "Très bien, Ñandù", SMSSegments{Encoding: EncodingGSM7, Units: 16, Segments: 1}},
+		{"single GSM-7 segment", strings.Repeat("a", 160), SMSSegments{Encoding: EncodingGSM7, Units: 160, Segments: 1}},
+		{"GSM-7 over one segment", strings.Repeat("a", 161), SMSSegments{Encoding: EncodingGSM7, Units: 161, Segments: 2}},
+		{"two full GSM-7 segments", strings.Repeat("a", 306), SMSSegments{Encoding: EncodingGSM7, Units: 306, Segments: 2}},
+		{"GSM-7 over two segments", strings.Repeat("a", 307), SMSSegments{Encoding: EncodingGSM7, Units: 307, Segments: 3}},
+		{"extension characters take two septets", strings.Repeat("€", 80), SMSSegments{Encoding: EncodingGSM7, Units: 160, Segments: 1}},
+		// 76 escape sequences fit in a 153 septet segment; one is never split across segments
+		{"escape sequences are not split", strings.Repeat("€", 81), SMSSegments{Encoding: EncodingGSM7, Units: 162, Segments: 2}},
+		{"UCS-2", "Привет", SMSSegments{Encoding: EncodingUCS2, Units: 6, Segments: 1}},
+		{"single UCS-2 segment", strings.Repeat("я", 70), SMSSegments{Encoding: EncodingUCS2, Units: 70, Segments: 1}},
+		{"UCS-2 over one segment", strings.Repeat("я", 71), SMSSegments{Encoding: EncodingUCS2, Units: 71, Segments: 2}},
+		{"one character switches to UCS-2", strings.Repeat("a", 100) + "ç", SMSSegments{Encoding: EncodingUCS2, Units: 101, Segments: 2}},
+		{"surrogate pairs take two units", strings.Repeat("😀", 35), SMSSegments{Encoding: EncodingUCS2, Units: 70, Segments: 1}},
+		// 33 pairs fit in a 67 unit segment
+		{"surrogate pairs are not split", strings.Repeat("😀", 36), SMSSegments{Encoding: EncodingUCS2, Units: 72, Segments: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CountSMSSegments(tt.text); got != tt.want {
+				t.Errorf("CountSMSSegments() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateSMS(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		maxSegments int
+		want        string
+	}{
+		{"fits", "Hello", 1, "Hello"},
+		{"no limit", strings.Repeat("a", 400), 0, strings.Repeat("a", 400)},
+		{"GSM-7 to one segment", strings.Repeat("a", 200), 1, strings.Repeat("a", 157) + "..."},
+		{"GSM-7 to two segments", strings.Repeat("a", 400), 2, strings.Repeat("a", 303) + "..."},
+		{"UCS-2 to one segment", strings.Repeat("я", 100), 1, strings.Repeat("я", 67) + "..."},
+		{"surrogate pairs are kept whole", strings.Repeat("😀", 40), 1, strings.Repeat("😀", 33) + "..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateSMS(tt.text, tt.maxSegments)
+			if got != tt.want {
+				t.Errorf("TruncateSMS() = %q (%d runes), want %q (%d runes)", got, len([]rune(got)), tt.want, len([]rune(tt.want)))
+			}
+			if tt.maxSegments > 0 && CountSMSSegments(got).Segments > tt.maxSegments {
+				t.Errorf("TruncateSMS() takes %d segments, want at most %d", CountSMSSegments(got).Segments, tt.maxSegments)
+			}
+		})
+	}
+}
@@ -0,0 +1,415 @@
+/*
+notifications/smtpPool.go
+Author: Akhil C
+Description: Pool of authenticated SMTP connections reused across emails, with STARTTLS or implicit TLS, PLAIN/LOGIN/CRAM-MD5 authentication, idle timeouts and health checks.
+*/
+
+package notifications
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cfg "github.com/akhilckenshi/notification/pkg/settings"
+)
+
+// Connection security modes of the SMTP pool
+const (
+	SMTPSecurityStartTLS = "starttls" // Plain connection upgraded with STARTTLS (port 587)
+	SMTPSecurityTLS      = "tls"      // Implicit TLS from the first byte (port 465)
+	SMTPSecurityNone     = "none"     // No encryption, for local relays only
+)
+
+// Authentication mechanisms of the SMTP pool
+const (
+	SMTPAuthPlain   = "plain"
+	SMTPAuthLogin   = "login"
+	SMTPAuthCRAMMD5 = "cram-md5"
+)
+
+// ErrPoolClosed is returned when sending through a closed pool
+var ErrPoolClosed = errors.New("smtp pool is closed")
+
+// SMTPPoolConfig configures an SMTPPool
+type SMTPPoolConfig struct {
+	Host                string        // SMTP server host
+	Port                int           // SMTP server port
+	Username            string        // Authentication user, no authentication when empty
+	Password            string        // Authentication password
+	Security            string        // One of SMTPSecurityStartTLS (default), SMTPSecurityTLS, SMTPSecurityNone
+	Auth                string        // One of SMTPAuthPlain, SMTPAuthLogin, SMTPAuthCRAMMD5; the best mechanism offered by the server when empty
+	InsecureSkipVerify  bool          // Skip verification of the server certificate
+	MaxConnections      int           // Maximum number of open connections
+	IdleTimeout         time.Duration // Idle connections older than this are closed
+	HealthCheckInterval time.Duration // Idle connections unused for longer than this are checked with NOOP before reuse
+	DialTimeout         time.Duration // Timeout to connect, negotiate TLS and authenticate
+	SendTimeout         time.Duration // Timeout to transmit a single message
+}
+
+// smtpConn is an open, authenticated SMTP session
+type smtpConn struct {
+	conn     net.Conn
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+// SMTPPool keeps authenticated SMTP connections open and reuses them for every message
+type SMTPPool struct {
+	config SMTPPoolConfig
+	slots  chan struct{} // One token per connection that may be open
+
+	mu     sync.Mutex
+	idle   []*smtpConn // Idle connections, most recently used last
+	closed bool
+	done   chan struct{}
+}
+
+// NewSMTPPool creates a pool for the given configuration. Connections are opened on demand.
+func NewSMTPPool(config SMTPPoolConfig) *SMTPPool {
+	if config.Security == "" {
+		config.Security = SMTPSecurityStartTLS
+	}
+	if config.MaxConnections <= 0 {
+		config.MaxConnections = 4
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 60 * time.Second
+	}
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = 15 * time.Second
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 10 * time.Second
+	}
+	if config.SendTimeout <= 0 {
+		config.SendTimeout = 60 * time.Second
+	}
+
+	pool := &SMTPPool{
+		config: config,
+		slots:  make(chan struct{}, config.MaxConnections),
+		done:   make(chan struct{}),
+	}
+	go pool.reapIdle()
+	return pool
+}
+
+// NewSMTPPoolFromConfig creates a pool from the application settings
+func NewSMTPPoolFromConfig() *SMTPPool {
+	return NewSMTPPool(smtpPoolConfigFromSettings())
+}
+
+// smtpPoolConfigFromSettings returns the pool configuration of the email settings. The SMTP_HOST,
+// SMTP_PORT, APP_EMILID and APP_EMAIL_PWD variables take precedence over the email section of config.yaml.
+func smtpPoolConfigFromSettings() SMTPPoolConfig {
+	email := cfg.Config.Email
+	config := SMTPPoolConfig{
+		Host:                firstNonEmpty(cfg.Config.SMTPHost, email.SmtpHost),
+		Port:                cfg.Config.SMTPPort,
+		Username:            firstNonEmpty(cfg.Config.AppEmailID, email.Username, email.Id),
+		Password:            firstNonEmpty(cfg.Config.AppEmailPassword, email.Pwd),
+		Security:            strings.ToLower(email.Security),
+		Auth:                strings.ToLower(email.Auth),
+		InsecureSkipVerify:  email.InsecureSkipVerify,
+		MaxConnections:      email.MaxConnections,
+		IdleTimeout:         time.Duration(email.IdleTimeout) * time.Second,
+		HealthCheckInterval: time.Duration(email.HealthCheckInterval) * time.Second,
+		DialTimeout:         time.Duration(email.DialTimeout) * time.Second,
+		SendTimeout:         time.Duration(email.SendTimeout) * time.Second,
+	}
+	if config.Port == 0 {
+		config.Port = email.SmtpPort
+	}
+	return config
+}
+
+// Send transmits msg from the envelope sender to the recipients over a pooled connection
+func (p *SMTPPool) Send(ctx context.Context, from string, to []string, msg []byte) error {
+	// Wait for a free connection slot
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-p.slots }()
+
+	c, err := p.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(p.config.SendTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+
+	if err := transmit(c.client, from, to, msg); err != nil {
+		// A rejection by the server leaves the session usable; anything else breaks it
+		var protocolErr *textproto.Error
+		if errors.As(err, &protocolErr) && c.client.Reset() == nil {
+			p.put(c)
+		} else {
+			c.close()
+		}
+		return err
+	}
+	p.put(c)
+	return nil
+}
+
+// Close closes the idle connections and stops the pool. Connections in use are closed when released.
+func (p *SMTPPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	close(p.done)
+	for _, c := range idle {
+		c.quit()
+	}
+}
+
+// get returns a healthy idle connection, or opens a new one
+func (p *SMTPPool) get(ctx context.Context) (*smtpConn, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+		if len(p.idle) == 0 {
+			p.mu.Unlock()
+			return p.dial(ctx)
+		}
+		c := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.mu.Unlock()
+
+		idleFor := time.Since(c.lastUsed)
+		if idleFor > p.config.IdleTimeout {
+			c.quit()
+			continue
+		}
+		if idleFor > p.config.HealthCheckInterval {
+			c.conn.SetDeadline(time.Now().Add(p.config.DialTimeout))
+			if err := c.client.Noop(); err != nil {
+				c.close()
+				continue
+			}
+		}
+		return c, nil
+	}
+}
+
+// put returns a connection to the idle list
+func (p *SMTPPool) put(c *smtpConn) {
+	c.conn.SetDeadline(time.Time{})
+	c.lastUsed = time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		go c.quit()
+		return
+	}
+	p.idle = append(p.idle, c)
+}
+
+// reapIdle periodically closes connections that stayed idle longer than the idle timeout
+func (p *SMTPPool) reapIdle() {
+	ticker := time.NewTicker(p.config.IdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		var expired []*smtpConn
+		p.mu.Lock()
+		kept := p.idle[:0]
+		for _, c := range p.idle {
+			if time.Since(c.lastUsed) > p.config.IdleTimeout {
+				expired = append(expired, c)
+			} else {
+				kept = append(kept, c)
+			}
+		}
+		p.idle = kept
+		p.mu.Unlock()
+
+		for _, c := range expired {
+			c.quit()
+		}
+	}
+}
+
+// dial opens, secures and authenticates a new SMTP session
+func (p *SMTPPool) dial(ctx context.Context) (*smtpConn, error) {
+	address := net.JoinHostPort(p.config.Host, strconv.Itoa(p.config.Port))
+	tlsConfig := &tls.Config{ServerName: p.config.Host, InsecureSkipVerify: p.config.InsecureSkipVerify}
+	dialer := &net.Dialer{Timeout: p.config.DialTimeout}
+
+	var conn net.Conn
+	var err error
+	if p.config.Security == SMTPSecurityTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	conn.SetDeadline(time.Now().Add(p.config.DialTimeout))
+
+	client, err := smtp.NewClient(conn, p.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c := &smtpConn{conn: conn, client: client}
+
+	if p.config.Security == SMTPSecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			c.close()
+			return nil, Permanent(fmt.Errorf("smtp server %s does not support STARTTLS", address))
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+
+	if p.config.Username != "" {
+		auth, err := p.auth(client)
+		if err != nil {
+			c.close()
+			return nil, err
+		}
+		if err := client.Auth(auth); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+
+	conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// auth returns the configured authentication mechanism, or the best one advertised by the server
+func (p *SMTPPool) auth(client *smtp.Client) (smtp.Auth, error) {
+	mechanism := p.config.Auth
+	if mechanism == "" {
+		_, advertised := client.Extension("AUTH")
+		offered := strings.Fields(strings.ToLower(advertised))
+		for _, candidate := range []string{SMTPAuthCRAMMD5, SMTPAuthPlain, SMTPAuthLogin} {
+			if slices.Contains(offered, candidate) {
+				mechanism = candidate
+				break
+			}
+		}
+	}
+
+	switch mechanism {
+	case SMTPAuthPlain:
+		return smtp.PlainAuth("", p.config.Username, p.config.Password, p.config.Host), nil
+	case SMTPAuthLogin:
+		return &loginAuth{username: p.config.Username, password: p.config.Password}, nil
+	case SMTPAuthCRAMMD5:
+		return smtp.CRAMMD5Auth(p.config.Username, p.config.Password), nil
+	case "":
+		return nil, Permanent(fmt.Errorf("smtp server offers no supported authentication mechanism"))
+	default:
+		return nil, Permanent(fmt.Errorf("unsupported smtp authentication mechanism %q", mechanism))
+	}
+}
+
+// transmit runs a single mail transaction on an open session
+func transmit(client *smtp.Client, from string, to []string, msg []byte) error {
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// quit ends the session politely, then closes the connection
+func (c *smtpConn) quit() {
+	c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := c.client.Quit(); err != nil {
+		c.close()
+	}
+}
+
+// close drops the connection without a QUIT
+func (c *smtpConn) close() {
+	c.client.Close()
+}
+
+// loginAuth implements the LOGIN authentication mechanism, which net/smtp does not provide
+type loginAuth struct {
+	username, password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSuffix(string(fromServer), ":")) {
+	case "username":
+		return []byte(a.username), nil
+	case "password":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN challenge %q", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
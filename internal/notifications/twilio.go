@@ -0,0 +1,94 @@
+/*
+notifications/twilio.go
+Author: Akhil C
+Description: Creates messages through the Twilio Messages API for the WhatsApp and SMS channels and reports the structured result.
+*/
+
+package notifications
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	cfg "github.com/akhilckenshi/notification/pkg/settings"
+
+	"github.com/twilio/twilio-go"
+	twclient "github.com/twilio/twilio-go/client"
+	openapi "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// TwilioStatusCallbackPath is the route receiving Twilio message status callbacks
+const TwilioStatusCallbackPath = "/api/v1/webhooks/twilio/status"
+
+// TwilioResult describes a message created through the Twilio API
+type TwilioResult struct {
+	SID          string // Message SID, used to correlate with the Twilio console and status callbacks
+	Status       string // Status reported by Twilio (queued, accepted, failed, ...)
+	ErrorCode    int    // Twilio error code, 0 when the message was accepted
+	ErrorMessage string // Description of the error
+}
+
+// TwilioMessageError is returned when Twilio created the message but immediately marked it as failed
+type TwilioMessageError struct {
+	SID     string
+	Code    int
+	Message string
+}
+
+func (e *TwilioMessageError) Error() string {
+	return fmt.Sprintf("twilio message %s failed with error %d: %s", e.SID, e.Code, e.Message)
+}
+
+// createTwilioMessage creates the message described by params with the configured Twilio account.
+// The result is filled in as far as Twilio reported it, including when an error is returned.
+func createTwilioMessage(params *openapi.CreateMessageParams) (TwilioResult, error) {
+	client := twilio.NewRestClientWithParams(twilio.ClientParams{
+		Username: cfg.Config.WhatsAppProviderKey,
+		Password: cfg.Config.WhatsAppProviderSecret,
+	})
+
+	// Delivery updates are only requested when the service is reachable from Twilio
+	if baseURL := cfg.Config.WhatsApp.BaseURL; baseURL != "" {
+		params.SetStatusCallback(strings.TrimRight(baseURL, "/") + TwilioStatusCallbackPath)
+	}
+
+	var result TwilioResult
+	resp, err := client.Api.CreateMessage(params)
+	if err != nil {
+		var restErr *twclient.TwilioRestError
+		if errors.As(err, &restErr) {
+			result.Status = "failed"
+			result.ErrorCode = restErr.Code
+			result.ErrorMessage = restErr.Message
+		}
+		return result, err
+	}
+	if resp == nil {
+		return result, errors.New("twilio returned an empty response")
+	}
+
+	if resp.Sid != nil {
+		result.SID = *resp.Sid
+	}
+	if resp.Status != nil {
+		result.Status = *resp.Status
+	}
+	if resp.ErrorCode != nil && *resp.ErrorCode != 0 {
+		result.ErrorCode = *resp.ErrorCode
+		if resp.ErrorMessage != nil {
+			result.ErrorMessage = *resp.ErrorMessage
+		}
+		return result, &TwilioMessageError{SID: result.SID, Code: result.ErrorCode, Message: result.ErrorMessage}
+	}
+	return result, nil
+}
+
+// deliveryResult copies a Twilio result into the result of a channel
+func (r TwilioResult) deliveryResult(result DeliveryResult) DeliveryResult {
+	result.ProviderMessageID = r.SID
+	result.ProviderStatus = r.Status
+	result.ErrorCode = r.ErrorCode
+	result.ErrorMessage = r.ErrorMessage
+	return result
+}
@@ -1,12 +1,13 @@
 package notifications
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
 
+	"github.com/akhilckenshi/notification/internal/models"
 	cfg "github.com/akhilckenshi/notification/pkg/settings"
 
-	"github.com/twilio/twilio-go"
 	openapi "github.com/twilio/twilio-go/rest/api/v2010"
 )
 
@@ -17,113 +18,74 @@ type TwilioConfig struct {
 	Url        string `mapstructure:"url"`
 }
 
-// SendWhatsAppMessage sends a WhatsApp notification using a third-party API like Twilio.
-func SendWhatsAppMessage(to, sub, message string) error {
-	accountSid := cfg.Config.WhatsAppProviderKey
-	authToken := cfg.Config.WhatsAppProviderSecret
-	fromNumber := "whatsapp:" + cfg.Config.WhatsAppFromNumber
-	toNumber := "whatsapp:" + to
-
-	client := twilio.NewRestClientWithParams(twilio.ClientParams{
-		Username: accountSid,
-		Password: authToken,
-	})
-
-	params := &openapi.CreateMessageParams{}
-	params.SetTo(toNumber)
-	params.SetFrom(fromNumber)
-	params.SetBody(fmt.Sprintf("%s: %s", sub, message))
-
-	resp, err := client.Api.CreateMessage(params)
-	if err != nil {
-		log.Printf("Error sending WhatsApp message: %v", err)
-	}
-
-	fmt.Printf("Message sent! SID: %s", *resp.Sid)
-
-	return nil
+// SessionStore reports whether the WhatsApp session window of a recipient is open
+type SessionStore interface {
+	SessionOpen(ctx context.Context, recipient string) (bool, error)
 }
 
-// // SendWhatsAppMessage sends a WhatsApp notification with a PDF attachment.
-// func SendWhatsAppMessage(to, sub, htmlContent string) error {
-// 	// Get Twilio credentials from the config
-// 	accountSid := cfg.Config.WhatsAppProviderKey
-// 	authToken := cfg.Config.WhatsAppProviderSecret
-// 	fromNumber := "whatsapp:" + cfg.Config.WhatsAppFromNumber
-// 	toNumber := "whatsapp:" + to
-
-// 	// Initialize the Twilio client
-// 	client := twilio.NewRestClientWithParams(twilio.ClientParams{
-// 		Username: accountSid,
-// 		Password: authToken,
-// 	})
-// 	fmt.Println("747474773")
-// 	// Convert HTML to PDF and store it locally
-// 	pdfPath, err := ConvertHTMLToPDF(htmlContent)
-// 	if err != nil {
-// 		return fmt.Errorf("error converting HTML to PDF: %v", err)
-// 	}
-// 	defer os.Remove(pdfPath) // Ensure file is removed after sending
-
-// 	// Send the PDF as a WhatsApp message
-// 	params := &openapi.CreateMessageParams{}
-// 	params.SetTo(toNumber)
-// 	params.SetFrom(fromNumber)
-// 	params.SetBody(fmt.Sprintf("%s: %s", sub, "Please find the PDF attached."))
-// 	params.SetMediaUrl([]string{fmt.Sprintf("file://%s", pdfPath)}) // Passing the URL as a slice of strings
+// WhatsAppNotifier delivers notifications of type "whatsapp" through Twilio. Free-form messages
+// are only accepted by WhatsApp within 24 hours of the last message of the recipient; outside
+// of that window the approved content template of the notification is sent instead.
+type WhatsAppNotifier struct {
+	sessions SessionStore
+}
 
-// 	// Send the message through Twilio
-// 	resp, err := client.Api.CreateMessage(params)
-// 	if err != nil {
-// 		log.Printf("Error sending WhatsApp message: %v", err)
-// 		return err
-// 	}
+// NewWhatsAppNotifier creates the WhatsApp channel. Without a session store, notifications
+// with a content template are always sent as templates.
+func NewWhatsAppNotifier(sessions SessionStore) *WhatsAppNotifier {
+	return &WhatsAppNotifier{sessions: sessions}
+}
 
-// 	// Log the response SID for tracking
-// 	fmt.Printf("Message sent! SID: %s\n", *resp.Sid)
-// 	return nil
-// }
+// Send implements Notifier for the WhatsApp channel
+func (w *WhatsAppNotifier) Send(ctx context.Context, notification *models.Notification) (DeliveryResult, error) {
+	result := DeliveryResult{Provider: "twilio", From: cfg.Config.WhatsAppFromNumber}
 
-// // ConvertHTMLToPDF converts HTML string content to a PDF and returns the file path.
-// func ConvertHTMLToPDF(htmlContent string) (string, error) {
-// 	fmt.Println("0000000")
-// 	// Set the wkhtmltopdf binary path if needed (optional)
-// 	// os.Setenv("WKHTMLTOPDF_BIN", "/path/to/wkhtmltopdf")
+	params := &openapi.CreateMessageParams{}
+	params.SetTo("whatsapp:" + notification.To)
+	params.SetFrom("whatsapp:" + cfg.Config.WhatsAppFromNumber)
+	if w.useContentTemplate(ctx, notification) {
+		params.SetContentSid(notification.ContentSID)
+		if len(notification.ContentVariables) > 0 {
+			variables, err := json.Marshal(notification.ContentVariables)
+			if err != nil {
+				return result, Permanent(err)
+			}
+			params.SetContentVariables(string(variables))
+		}
+	} else {
+		params.SetBody(whatsAppBody(notification.Subject, notification.Message))
+		if len(notification.MediaURLs) > 0 {
+			params.SetMediaUrl(notification.MediaURLs)
+		}
+	}
 
-// 	// Create a new PDF generator instance
-// 	pdf, _ := wkhtmltopdf.NewPDFGenerator()
-// 	fmt.Println("ddddd", pdf)
-// 	// if err != nil {
-// 	// 	fmt.Println("first")
-// 	// 	return "", fmt.Errorf("failed to create PDF generator: %v", err)
-// 	// }
+	twilioResult, err := createTwilioMessage(params)
+	return twilioResult.deliveryResult(result), err
+}
 
-// 	// Convert HTML content to io.Reader using bytes.NewReader
-// 	page := wkhtmltopdf.NewPageReader(bytes.NewReader([]byte(htmlContent)))
-// 	fmt.Println("eee")
-// 	// Add the page to the PDF generator
-// 	pdf.AddPage(page)
+// useContentTemplate decides between the content template and the free-form message of a
+// notification. The free-form message is preferred while the session window is open.
+func (w *WhatsAppNotifier) useContentTemplate(ctx context.Context, notification *models.Notification) bool {
+	if notification.ContentSID == "" {
+		return false
+	}
+	if notification.Message == "" && len(notification.MediaURLs) == 0 {
+		return true
+	}
+	if w.sessions == nil {
+		return true
+	}
+	open, err := w.sessions.SessionOpen(ctx, notification.To)
+	if err != nil {
+		return true // A template is accepted whether or not the window is open
+	}
+	return !open
+}
 
-// 	// Create a temporary file for the PDF
-// 	fmt.Println("111")
-// 	pdfDir := "pdf"
-// 	if err := os.MkdirAll(pdfDir, os.ModePerm); err != nil {
-// 		return "", fmt.Errorf("failed to create PDF directory: %v", err)
-// 	}
-// 	tempFile := fmt.Sprintf("%s/temp_pdf_%d.pdf", pdfDir, os.Getpid())
-// 	fmt.Println("22222")
-// 	// Create the PDF
-// 	err := pdf.Create()
-// 	if err != nil {
-// 		return "", fmt.Errorf("failed to create PDF: %v", err)
-// 	}
-// 	fmt.Println("3333")
-// 	// Write the generated PDF to the file
-// 	err = pdf.WriteFile(tempFile)
-// 	if err != nil {
-// 		return "", fmt.Errorf("failed to write PDF to file: %v", err)
-// 	}
-// 	fmt.Println("444")
-// 	// Return the path to the temporary PDF file
-// 	return tempFile, nil
-// }
+// whatsAppBody returns the free-form text of a message, prefixed with the subject when there is one
+func whatsAppBody(subject, message string) string {
+	if subject == "" {
+		return message
+	}
+	return fmt.Sprintf("%s: %s", subject, message)
+}
@@ -0,0 +1,225 @@
+/*
+ratelimit/ratelimit.go
+Author: Akhil C
+Description: Token bucket rate limits per channel, provider and organization, with utilization metrics.
+*/
+
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	cfg "github.com/akhilckenshi/notification/pkg/settings"
+)
+
+// Scopes of the rate limits
+const (
+	ScopeChannel      = "channel"      // Limit keyed by notification type
+	ScopeProvider     = "provider"     // Limit keyed by provider name (e.g. smtp, ses, twilio)
+	ScopeOrganization = "organization" // Limit keyed by organization ID
+)
+
+// bucketIdleTTL is how long an organization bucket stays unused before it is evicted. Only full
+// buckets are evicted; a new bucket starts full, so eviction does not change the limit.
+const bucketIdleTTL = 10 * time.Minute
+
+// Key identifies the bucket of a limit
+type Key struct {
+	Scope string
+	Name  string
+}
+
+// LimitedError is returned when a message cannot be sent yet because a rate limit is reached
+type LimitedError struct {
+	Key        Key
+	RetryAfter time.Duration // Time until the bucket holds a token again
+}
+
+func (e *LimitedError) Error() string {
+	return fmt.Sprintf("%s %s rate limit reached, retry in %s", e.Key.Scope, e.Key.Name, e.RetryAfter.Round(time.Millisecond))
+}
+
+// tokenBucket is a token bucket refilled continuously at rate tokens per second, holding at most burst tokens
+type tokenBucket struct {
+	rate    float64
+	burst   float64
+	tokens  float64
+	updated time.Time
+	used    time.Time // Last time a message asked for a token
+	taken   uint64    // Tokens taken since the bucket was created
+	limited uint64    // Requests that found the bucket empty
+}
+
+// newBucket creates a full bucket
+func newBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), updated: now, used: now}
+}
+
+// refill adds the tokens earned since the last update
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.updated = now
+	}
+}
+
+// wait returns how long until the bucket holds a token, 0 when it holds one now
+func (b *tokenBucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// Stats describes the current state of a bucket
+type Stats struct {
+	Scope       string  `json:"scope"`
+	Name        string  `json:"name"`
+	Rate        float64 `json:"rate"`        // Tokens added per second
+	Burst       int     `json:"burst"`       // Capacity of the bucket
+	Tokens      float64 `json:"tokens"`      // Tokens currently available
+	Utilization float64 `json:"utilization"` // Share of the capacity in use, from 0 (idle) to 1 (limited)
+	Taken       uint64  `json:"taken"`       // Messages let through since the bucket was created
+	Limited     uint64  `json:"limited"`     // Messages deferred since the bucket was created
+}
+
+// Limiter holds the buckets of the configured limits. Buckets are created on first use; keys
+// without a configured limit are not limited. Idle organization buckets are evicted, so the
+// buckets do not grow with every organization ever seen.
+type Limiter struct {
+	mu       sync.Mutex
+	limits   map[string]map[string]cfg.RateConfig // Limits by scope and name
+	defaults map[string]cfg.RateConfig            // Limits applying to every name of a scope (organizations)
+	buckets  map[Key]*tokenBucket
+	swept    time.Time // Last eviction of idle buckets
+}
+
+// New creates a limiter. limits are keyed by scope and name; defaults, keyed by scope, apply to
+// the names of a scope without their own limit.
+func New(limits map[string]map[string]cfg.RateConfig, defaults map[string]cfg.RateConfig) *Limiter {
+	return &Limiter{limits: limits, defaults: defaults, buckets: map[Key]*tokenBucket{}}
+}
+
+// NewFromConfig creates the limiter configured under rateLimits
+func NewFromConfig() *Limiter {
+	conf := cfg.Config.RateLimits
+	limits := map[string]map[string]cfg.RateConfig{
+		ScopeChannel:      conf.Channels,
+		ScopeProvider:     conf.Providers,
+		ScopeOrganization: conf.Organizations,
+	}
+	return New(limits, map[string]cfg.RateConfig{ScopeOrganization: conf.Organization})
+}
+
+// limit returns the limit of a key, false when the key is not limited
+func (l *Limiter) limit(key Key) (cfg.RateConfig, bool) {
+	limit, ok := l.limits[key.Scope][key.Name]
+	if !ok {
+		limit = l.defaults[key.Scope]
+	}
+	if limit.PerSecond <= 0 {
+		return limit, false
+	}
+	if limit.Burst < 1 {
+		limit.Burst = int(math.Ceil(limit.PerSecond))
+	}
+	return limit, true
+}
+
+// bucketFor returns the bucket of a key, nil when the key is not limited. l.mu must be held.
+func (l *Limiter) bucketFor(key Key, now time.Time) *tokenBucket {
+	if bucket, ok := l.buckets[key]; ok {
+		return bucket
+	}
+	limit, ok := l.limit(key)
+	if !ok {
+		return nil
+	}
+	bucket := newBucket(limit.PerSecond, limit.Burst, now)
+	l.buckets[key] = bucket
+	return bucket
+}
+
+// Take takes a token from the bucket of every key when they all hold one. Otherwise nothing is
+// taken and a *LimitedError names the bucket that is limited for the longest time.
+func (l *Limiter) Take(keys ...Key) error {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.evictIdle(now)
+
+	var limited *LimitedError
+	buckets := make([]*tokenBucket, 0, len(keys))
+	for _, key := range keys {
+		bucket := l.bucketFor(key, now)
+		if bucket == nil {
+			continue
+		}
+		bucket.refill(now)
+		bucket.used = now
+		if wait := bucket.wait(); wait > 0 && (limited == nil || wait > limited.RetryAfter) {
+			limited = &LimitedError{Key: key, RetryAfter: wait}
+		}
+		buckets = append(buckets, bucket)
+	}
+	if limited != nil {
+		l.buckets[limited.Key].limited++
+		return limited
+	}
+	for _, bucket := range buckets {
+		bucket.tokens--
+		bucket.taken++
+	}
+	return nil
+}
+
+// evictIdle removes the organization buckets that are full and have not been used for
+// bucketIdleTTL. It runs at most once per bucketIdleTTL. l.mu must be held.
+func (l *Limiter) evictIdle(now time.Time) {
+	if now.Sub(l.swept) < bucketIdleTTL {
+		return
+	}
+	l.swept = now
+	for key, bucket := range l.buckets {
+		if key.Scope != ScopeOrganization || now.Sub(bucket.used) < bucketIdleTTL {
+			continue
+		}
+		bucket.refill(now)
+		if bucket.tokens >= bucket.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Stats returns the state of every bucket in use, sorted by scope and name
+func (l *Limiter) Stats() []Stats {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make([]Stats, 0, len(l.buckets))
+	for key, bucket := range l.buckets {
+		bucket.refill(now)
+		stats = append(stats, Stats{
+			Scope:       key.Scope,
+			Name:        key.Name,
+			Rate:        bucket.rate,
+			Burst:       int(bucket.burst),
+			Tokens:      math.Floor(bucket.tokens*100) / 100,
+			Utilization: math.Round((1-bucket.tokens/bucket.burst)*100) / 100,
+			Taken:       bucket.taken,
+			Limited:     bucket.limited,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Scope != stats[j].Scope {
+			return stats[i].Scope < stats[j].Scope
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
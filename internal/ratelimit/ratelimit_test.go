@@ -0,0 +1,135 @@
+/*
+ratelimit/ratelimit_test.go
+Author: Akhil C
+Description: Tests of the token bucket refill, of taking tokens from several limits at once and of the eviction of idle buckets.
+*/
+
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	cfg "github.com/akhilckenshi/notification/pkg/settings"
+)
+
+func TestTokenBucketRefill(t *testing.T) {
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		rate     float64
+		burst    int
+		taken    float64       // Tokens taken at start
+		elapsed  time.Duration // Time until the refill
+		want     float64       // Tokens after the refill
+		wantWait time.Duration // Wait for the next token after the refill
+	}{
+		{name: "full bucket stays full", rate: 2, burst: 5, elapsed: time.Minute, want: 5},
+		{name: "empty bucket", rate: 2, burst: 5, taken: 5, want: 0, wantWait: 500 * time.Millisecond},
+		{name: "partial refill", rate: 2, burst: 5, taken: 5, elapsed: 1500 * time.Millisecond, want: 3},
+		{name: "fraction of a token", rate: 2, burst: 5, taken: 5, elapsed: 200 * time.Millisecond, want: 0.4, wantWait: 300 * time.Millisecond},
+		{name: "capped at the burst", rate: 2, burst: 5, taken: 5, elapsed: time.Hour, want: 5},
+		{name: "slow rate", rate: 0.1, burst: 1, taken: 1, elapsed: 5 * time.Second, want: 0.5, wantWait: 5 * time.Second},
+		{name: "clock going back", rate: 2, burst: 5, taken: 5, elapsed: -time.Second, want: 0, wantWait: 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket := newBucket(tt.rate, tt.burst, start)
+			bucket.tokens -= tt.taken
+			bucket.refill(start.Add(tt.elapsed))
+
+			if diff := bucket.tokens - tt.want; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("tokens = %v, want %v", bucket.tokens, tt.want)
+			}
+			if diff := bucket.wait() - tt.wantWait; diff > time.Microsecond || diff < -time.Microsecond {
+				t.Errorf("wait() = %v, want %v", bucket.wait(), tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestLimiterTake(t *testing.T) {
+	channel := Key{Scope: ScopeChannel, Name: "sms"}
+	organization := Key{Scope: ScopeOrganization, Name: "652f1c2e9b1e8a0001a1b2c3"}
+	unlimited := Key{Scope: ScopeProvider, Name: "smtp"}
+
+	// Slow rates, so the buckets do not refill during the test
+	limits := map[string]map[string]cfg.RateConfig{
+		ScopeChannel: {"sms": {PerSecond: 0.001, Burst: 1}},
+	}
+	defaults := map[string]cfg.RateConfig{ScopeOrganization: {PerSecond: 0.001, Burst: 2}}
+
+	tests := []struct {
+		name        string
+		keys        []Key
+		wantLimited *Key // Key named by the LimitedError, nil when the take succeeds
+	}{
+		{name: "first take", keys: []Key{channel, organization}},
+		{name: "channel empty", keys: []Key{channel, organization}, wantLimited: &channel},
+		// The failed take above left the token of the organization
+		{name: "organization alone", keys: []Key{organization}},
+		{name: "organization empty", keys: []Key{organization}, wantLimited: &organization},
+		{name: "unlimited key", keys: []Key{unlimited}},
+	}
+	limiter := New(limits, defaults)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := limiter.Take(tt.keys...)
+			if tt.wantLimited == nil {
+				if err != nil {
+					t.Fatalf("Take() error = %v", err)
+				}
+				return
+			}
+			var limited *LimitedError
+			if !errors.As(err, &limited) {
+				t.Fatalf("Take() error = %v, want a *LimitedError", err)
+			}
+			if limited.Key != *tt.wantLimited || limited.RetryAfter <= 0 {
+				t.Errorf("Take() limited by %+v in %v, want %+v", limited.Key, limited.RetryAfter, *tt.wantLimited)
+			}
+		})
+	}
+
+	stats := limiter.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("Stats() = %+v, want the channel and organization buckets", stats)
+	}
+	for _, stat := range stats {
+		if stat.Taken == 0 || stat.Limited != 1 {
+			t.Errorf("%s %s taken %d, limited %d; want taken and limited once", stat.Scope, stat.Name, stat.Taken, stat.Limited)
+		}
+	}
+}
+
+func TestLimiterEvictIdle(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	channel := Key{Scope: ScopeChannel, Name: "email"}
+	tests := []struct {
+		name      string
+		key       Key
+		lastUsed  time.Duration // Time since the bucket was last used
+		taken     float64       // Tokens missing from the bucket at the sweep
+		wantEvict bool
+	}{
+		{name: "idle and full", key: Key{Scope: ScopeOrganization, Name: "idle"}, lastUsed: 2 * bucketIdleTTL, wantEvict: true},
+		{name: "recently used", key: Key{Scope: ScopeOrganization, Name: "active"}, lastUsed: time.Minute},
+		{name: "idle but not full", key: Key{Scope: ScopeOrganization, Name: "drained"}, lastUsed: 2 * bucketIdleTTL, taken: 1},
+		{name: "channel buckets are kept", key: channel, lastUsed: 2 * bucketIdleTTL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := New(nil, nil)
+			bucket := newBucket(0.001, 5, now.Add(-tt.lastUsed))
+			bucket.tokens -= tt.taken
+			bucket.updated = now // Tokens are counted at the sweep
+			limiter.buckets[tt.key] = bucket
+
+			limiter.evictIdle(now)
+			if _, ok := limiter.buckets[tt.key]; ok == tt.wantEvict {
+				t.Errorf("bucket kept = %v, want %v", ok, !tt.wantEvict)
+			}
+		})
+	}
+}
@@ -8,16 +8,32 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"time"
 
-	"github.com/akhilckenshi/Notification/pkg/logger"
 	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/pkg/logger"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrNotificationNotFound is returned when no notification matches the lookup
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// ErrStaleStatus is returned when a status transition no longer applies to the stored notification
+var ErrStaleStatus = errors.New("notification status has changed")
+
+// ErrNotificationLeased is returned when a notification cannot be leased: another worker holds its
+// lease or it is not waiting to be sent anymore
+var ErrNotificationLeased = errors.New("notification is leased or not waiting to be sent")
+
+// ErrDuplicateNotification is returned when a notification with the same idempotency key already exists
+var ErrDuplicateNotification = errors.New("notification with the same idempotency key already exists")
+
 // Warehouse handles interactions with the notification collection
 type Notification struct {
 	db *mongo.Collection
@@ -35,14 +51,220 @@ func NewNotificationRepo(cl interface{}, dbName string) *Notification {
 	}
 }
 
-// Store Notification information from the Message
-func (repo *Notification) StoreNotificationInformation(notification models.Notification) error {
+// EnsureIndexes creates the indexes required by the notification collection
+func (repo *Notification) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			// Idempotency keys are unique; notifications without a key are not indexed
+			Keys: bson.D{{Key: "idempotency_key", Value: 1}},
+			Options: options.Index().
+				SetName("idempotency_key_unique").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"idempotency_key": bson.M{"$type": "string"}}),
+		},
+		{
+			// Recovery sweeps look up the notifications waiting to be sent
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("status"),
+		},
+		{
+			// Status callbacks of providers look notifications up by the provider message ID
+			Keys: bson.D{{Key: "provider_message_id", Value: 1}},
+			Options: options.Index().
+				SetName("provider_message_id").
+				SetPartialFilterExpression(bson.M{"provider_message_id": bson.M{"$gt": ""}}),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create notification indexes: %v", err)
+	}
+	return nil
+}
+
+// Store Notification information from the Message and assign the generated ID to it.
+// ErrDuplicateNotification is returned if the idempotency key is already taken.
+func (repo *Notification) StoreNotificationInformation(ctx context.Context, notification *models.Notification) error {
 	notification.UpdatedAt = time.Now()
-	_, err := repo.db.InsertOne(context.TODO(), notification)
+	result, err := repo.db.InsertOne(ctx, notification)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateNotification
+	}
 	if err != nil {
 		errStr := fmt.Sprintf("failed to store information: %v", err)
 		logger.Log.Error(errStr)
-		return fmt.Errorf(errStr)
+		return errors.New(errStr)
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		notification.ID = id
+	}
+	return nil
+}
+
+// StoreNotifications inserts several notifications at once. Each notification is assigned
+// its ID before insertion; the returned slice holds the insert error of each notification
+// in input order (nil on success), and failed notifications keep a zero ID.
+func (repo *Notification) StoreNotifications(ctx context.Context, notifications []*models.Notification) []error {
+	errs := make([]error, len(notifications))
+	if len(notifications) == 0 {
+		return errs
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, len(notifications))
+	for i, notification := range notifications {
+		notification.ID = primitive.NewObjectID()
+		notification.UpdatedAt = now
+		docs[i] = notification
+	}
+
+	// Unordered inserts keep going after a failed document
+	_, err := repo.db.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if err == nil {
+		return errs
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+		for _, writeErr := range bulkErr.WriteErrors {
+			if mongo.IsDuplicateKeyError(writeErr) {
+				errs[writeErr.Index] = ErrDuplicateNotification
+				continue
+			}
+			errs[writeErr.Index] = fmt.Errorf("failed to store information: %s", writeErr.Message)
+		}
+	} else {
+		for i := range errs {
+			errs[i] = fmt.Errorf("failed to store information: %v", err)
+		}
+	}
+	logger.Log.Error(fmt.Sprintf("failed to store notification batch: %v", err))
+
+	for i, notification := range notifications {
+		if errs[i] != nil {
+			notification.ID = primitive.NilObjectID
+		}
+	}
+	return errs
+}
+
+// RecordTransition persists a status transition of the notification with the given ID.
+// The update only applies while the stored status still equals transition.From, so
+// concurrent updates cannot overwrite a newer status; ErrStaleStatus is returned otherwise.
+// Additional fields to set alongside the status can be passed in fields.
+func (repo *Notification) RecordTransition(ctx context.Context, id primitive.ObjectID, transition models.StatusTransition, fields bson.M) error {
+	set := bson.M{
+		"status":     transition.Status,
+		"updated_at": transition.At,
+	}
+	for key, value := range fields {
+		set[key] = value
+	}
+
+	filter := bson.M{"_id": id, "status": transition.From}
+	update := bson.M{
+		"$set":  set,
+		"$push": bson.M{"status_history": transition},
+	}
+	result, err := repo.db.UpdateOne(ctx, filter, update)
+	if err != nil {
+		errStr := fmt.Sprintf("failed to record status %s for notification %s: %v", transition.Status, id.Hex(), err)
+		logger.Log.Error(errStr)
+		return errors.New(errStr)
+	}
+	if result.MatchedCount == 0 {
+		return ErrStaleStatus
+	}
+	return nil
+}
+
+// Lease takes the lease of a notification waiting to be sent (see models.PendingStatuses) under
+// leaseID until the given time, and returns the stored notification. ErrNotificationLeased is
+// returned when another worker holds an active lease or the notification is not pending anymore.
+func (repo *Notification) Lease(ctx context.Context, id primitive.ObjectID, leaseID string, until time.Time) (*models.Notification, error) {
+	filter := bson.M{
+		"_id":              id,
+		"status":           bson.M{"$in": models.PendingStatuses},
+		"lease_expires_at": bson.M{"$not": bson.M{"$gt": time.Now()}},
+	}
+	update := bson.M{"$set": bson.M{"lease_id": leaseID, "lease_expires_at": until}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var notification models.Notification
+	err := repo.db.FindOneAndUpdate(ctx, filter, update, opts).Decode(&notification)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotificationLeased
+	}
+	if err != nil {
+		errStr := fmt.Sprintf("failed to lease notification %s: %v", id.Hex(), err)
+		logger.Log.Error(errStr)
+		return nil, errors.New(errStr)
+	}
+	return &notification, nil
+}
+
+// ReleaseLease removes the lease a worker holds on a notification under leaseID
+func (repo *Notification) ReleaseLease(ctx context.Context, id primitive.ObjectID, leaseID string) error {
+	_, err := repo.db.UpdateOne(ctx,
+		bson.M{"_id": id, "lease_id": leaseID},
+		bson.M{"$unset": bson.M{"lease_id": "", "lease_expires_at": ""}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release the lease of notification %s: %v", id.Hex(), err)
+	}
+	return nil
+}
+
+// ListPending returns up to limit notifications waiting to be sent (see models.PendingStatuses) that
+// no worker holds and that have not changed since updatedBefore, in ID order starting after the
+// given ID
+func (repo *Notification) ListPending(ctx context.Context, updatedBefore time.Time, after primitive.ObjectID, limit int64) ([]*models.Notification, error) {
+	filter := bson.M{
+		"_id":              bson.M{"$gt": after},
+		"status":           bson.M{"$in": models.PendingStatuses},
+		"updated_at":       bson.M{"$lt": updatedBefore},
+		"lease_expires_at": bson.M{"$not": bson.M{"$gt": time.Now()}},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+	cursor, err := repo.db.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending notifications: %v", err)
+	}
+	var notifications []*models.Notification
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, fmt.Errorf("failed to list pending notifications: %v", err)
+	}
+	return notifications, nil
+}
+
+// FindByIdempotencyKeys returns the notifications holding the given idempotency keys, keyed by idempotency key
+func (repo *Notification) FindByIdempotencyKeys(ctx context.Context, keys []string) (map[string]*models.Notification, error) {
+	found := make(map[string]*models.Notification)
+	if len(keys) == 0 {
+		return found, nil
+	}
+
+	notifications, err := repo.ListNotifications(ctx, bson.M{"idempotency_key": bson.M{"$in": keys}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up idempotency keys: %v", err)
+	}
+	for _, notification := range notifications {
+		found[notification.IdempotencyKey] = notification
+	}
+	return found, nil
+}
+
+// ReleaseIdempotencyKeys removes the idempotency key from notifications whose dedup window
+// has ended, so a new notification can be stored with the same key
+func (repo *Notification) ReleaseIdempotencyKeys(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := repo.db.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		bson.M{"$unset": bson.M{"idempotency_key": ""}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency keys: %v", err)
 	}
 	return nil
 }
@@ -59,3 +281,43 @@ func (repo *Notification) ListNotifications(ctx context.Context, filter bson.M)
 	}
 	return notificaitons, nil
 }
+
+// FindByID returns the notification with the given ID
+func (repo *Notification) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Notification, error) {
+	var notification models.Notification
+	err := repo.db.FindOne(ctx, bson.M{"_id": id}).Decode(&notification)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find notification %s: %v", id.Hex(), err)
+	}
+	return &notification, nil
+}
+
+// FindByProviderMessageID returns the notification the provider identifies with messageID
+func (repo *Notification) FindByProviderMessageID(ctx context.Context, messageID string) (*models.Notification, error) {
+	var notification models.Notification
+	err := repo.db.FindOne(ctx, bson.M{"provider_message_id": messageID}).Decode(&notification)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find notification by provider message ID %s: %v", messageID, err)
+	}
+	return &notification, nil
+}
+
+// UpdateFields sets fields of a notification without changing its status
+func (repo *Notification) UpdateFields(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	set := bson.M{"updated_at": time.Now()}
+	for key, value := range fields {
+		set[key] = value
+	}
+	if _, err := repo.db.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set}); err != nil {
+		errStr := fmt.Sprintf("failed to update notification %s: %v", id.Hex(), err)
+		logger.Log.Error(errStr)
+		return errors.New(errStr)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+/*
+repo/preference.go
+Author: Akhil C
+Description: Repository for the notification preferences of recipients in MongoDB.
+*/
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrPreferenceNotFound is returned when a recipient has no stored preferences
+var ErrPreferenceNotFound = errors.New("preferences not found")
+
+// Preference handles interactions with the preference collection
+type Preference struct {
+	db *mongo.Collection
+}
+
+// NewPreferenceRepo initializes the preference repository with a MongoDB collection
+func NewPreferenceRepo(cl interface{}, dbName string) *Preference {
+	if mongoClient, ok := cl.(*mongo.Client); ok {
+		collectionName := models.Preference{}.TableName()
+		collection := mongoClient.Database(dbName).Collection(collectionName)
+
+		return &Preference{db: collection}
+	} else {
+		return nil
+	}
+}
+
+// EnsureIndexes creates the indexes required by the preference collection
+func (repo *Preference) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "organization_id", Value: 1}, {Key: "recipient", Value: 1}},
+		Options: options.Index().SetName("preference_recipient_unique").SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create preference indexes: %v", err)
+	}
+	return nil
+}
+
+// Save stores the preferences of a recipient, replacing any previous preferences.
+// The stored preferences are decoded into preference.
+func (repo *Preference) Save(ctx context.Context, preference *models.Preference) error {
+	now := time.Now()
+	preference.Recipient = models.NormalizeRecipient(preference.Recipient)
+
+	filter := bson.M{"organization_id": preference.OrganizationID, "recipient": preference.Recipient}
+	update := bson.M{
+		"$set": bson.M{
+			"time_zone":  preference.TimeZone,
+			"categories": preference.Categories,
+			"updated_at": now,
+		},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := repo.db.FindOneAndUpdate(ctx, filter, update, opts).Decode(preference); err != nil {
+		errStr := fmt.Sprintf("failed to store preferences of %s: %v", preference.Recipient, err)
+		logger.Log.Error(errStr)
+		return errors.New(errStr)
+	}
+	return nil
+}
+
+// OptOut opts a recipient out of a category on every channel, creating the preferences of the
+// recipient when needed. The other preferences of the recipient are kept.
+func (repo *Preference) OptOut(ctx context.Context, orgID primitive.ObjectID, recipient, category string) error {
+	now := time.Now()
+	filter := bson.M{"organization_id": orgID, "recipient": models.NormalizeRecipient(recipient)}
+	update := bson.M{
+		"$set":         bson.M{"categories." + category + ".opt_out": true, "updated_at": now},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	if _, err := repo.db.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		errStr := fmt.Sprintf("failed to opt %s out of %s notifications: %v", recipient, category, err)
+		logger.Log.Error(errStr)
+		return errors.New(errStr)
+	}
+	return nil
+}
+
+// Find returns the preferences of a recipient of an organization
+func (repo *Preference) Find(ctx context.Context, orgID primitive.ObjectID, recipient string) (*models.Preference, error) {
+	filter := bson.M{"organization_id": orgID, "recipient": models.NormalizeRecipient(recipient)}
+	var preference models.Preference
+	if err := repo.db.FindOne(ctx, filter).Decode(&preference); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrPreferenceNotFound
+		}
+		return nil, err
+	}
+	return &preference, nil
+}
+
+// List lists the preferences of every recipient of an organization
+func (repo *Preference) List(ctx context.Context, orgID primitive.ObjectID) ([]*models.Preference, error) {
+	cursor, err := repo.db.Find(ctx, bson.M{"organization_id": orgID}, options.Find().SetSort(bson.D{{Key: "recipient", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	preferences := []*models.Preference{}
+	if err := cursor.All(ctx, &preferences); err != nil {
+		return nil, err
+	}
+	return preferences, nil
+}
+
+// Delete removes the preferences of a recipient, who then receives every category again
+func (repo *Preference) Delete(ctx context.Context, orgID primitive.ObjectID, recipient string) error {
+	result, err := repo.db.DeleteOne(ctx, bson.M{"organization_id": orgID, "recipient": models.NormalizeRecipient(recipient)})
+	if err != nil {
+		return fmt.Errorf("failed to delete preferences of %s: %v", recipient, err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrPreferenceNotFound
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+/*
+repo/quietHours.go
+Author: Akhil C
+Description: Repository for the quiet hours of organizations and recipients in MongoDB.
+*/
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrQuietHoursNotFound is returned when no quiet hours are stored for an organization or recipient
+var ErrQuietHoursNotFound = errors.New("quiet hours not found")
+
+// QuietHours handles interactions with the quiet hours collection
+type QuietHours struct {
+	db *mongo.Collection
+}
+
+// NewQuietHoursRepo initializes the quiet hours repository with a MongoDB collection
+func NewQuietHoursRepo(cl interface{}, dbName string) *QuietHours {
+	if mongoClient, ok := cl.(*mongo.Client); ok {
+		collectionName := models.QuietHours{}.TableName()
+		collection := mongoClient.Database(dbName).Collection(collectionName)
+
+		return &QuietHours{db: collection}
+	} else {
+		return nil
+	}
+}
+
+// EnsureIndexes creates the indexes required by the quiet hours collection
+func (repo *QuietHours) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "organization_id", Value: 1}, {Key: "recipient", Value: 1}},
+		Options: options.Index().SetName("quiet_hours_recipient_unique").SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create quiet hours indexes: %v", err)
+	}
+	return nil
+}
+
+// Save stores quiet hours, replacing the quiet hours of the same organization and recipient.
+// The stored quiet hours are decoded into quietHours.
+func (repo *QuietHours) Save(ctx context.Context, quietHours *models.QuietHours) error {
+	now := time.Now()
+	if quietHours.Recipient != "" {
+		quietHours.Recipient = models.NormalizeRecipient(quietHours.Recipient)
+	}
+
+	filter := bson.M{"organization_id": quietHours.OrganizationID, "recipient": quietHours.Recipient}
+	update := bson.M{
+		"$set": bson.M{
+			"time_zone":           quietHours.TimeZone,
+			"windows":             quietHours.Windows,
+			"channels":            quietHours.Channels,
+			"defer_high_priority": quietHours.DeferHighPriority,
+			"updated_at":          now,
+		},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := repo.db.FindOneAndUpdate(ctx, filter, update, opts).Decode(quietHours); err != nil {
+		errStr := fmt.Sprintf("failed to store quiet hours: %v", err)
+		logger.Log.Error(errStr)
+		return errors.New(errStr)
+	}
+	return nil
+}
+
+// List lists the quiet hours of an organization and its recipients
+func (repo *QuietHours) List(ctx context.Context, orgID primitive.ObjectID) ([]*models.QuietHours, error) {
+	cursor, err := repo.db.Find(ctx, bson.M{"organization_id": orgID}, options.Find().SetSort(bson.D{{Key: "recipient", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	quietHours := []*models.QuietHours{}
+	if err := cursor.All(ctx, &quietHours); err != nil {
+		return nil, err
+	}
+	return quietHours, nil
+}
+
+// Delete removes the quiet hours of a recipient, or of the organization when recipient is empty
+func (repo *QuietHours) Delete(ctx context.Context, orgID primitive.ObjectID, recipient string) error {
+	if recipient != "" {
+		recipient = models.NormalizeRecipient(recipient)
+	}
+	result, err := repo.db.DeleteOne(ctx, bson.M{"organization_id": orgID, "recipient": recipient})
+	if err != nil {
+		return fmt.Errorf("failed to delete quiet hours: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrQuietHoursNotFound
+	}
+	return nil
+}
+
+// ForRecipient returns the quiet hours applying to a recipient: its own, or else those of its
+// organization. ErrQuietHoursNotFound is returned when there are none.
+func (repo *QuietHours) ForRecipient(ctx context.Context, orgID primitive.ObjectID, recipient string) (*models.QuietHours, error) {
+	filter := bson.M{
+		"organization_id": orgID,
+		"recipient":       bson.M{"$in": bson.A{models.NormalizeRecipient(recipient), ""}},
+	}
+	// The recipient sorts after the empty recipient of the organization
+	opts := options.FindOne().SetSort(bson.D{{Key: "recipient", Value: -1}})
+	var quietHours models.QuietHours
+	if err := repo.db.FindOne(ctx, filter, opts).Decode(&quietHours); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrQuietHoursNotFound
+		}
+		return nil, err
+	}
+	return &quietHours, nil
+}
@@ -0,0 +1,158 @@
+/*
+repo/schedule.go
+Author: Akhil C
+Description: Repository for notifications held until their send time, claimed with leases by the scheduler.
+*/
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrScheduleNotFound is returned when a notification is not (or no longer) held by a schedule
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// ErrScheduleRejected is returned when the database rejects a schedule (e.g. it fails the validation
+// of the collection). Unlike connection failures, storing the schedule again fails the same way.
+var ErrScheduleRejected = errors.New("schedule rejected by the database")
+
+// Schedule handles interactions with the schedule collection
+type Schedule struct {
+	db *mongo.Collection
+}
+
+// NewScheduleRepo initializes the schedule repository with a MongoDB collection
+func NewScheduleRepo(cl interface{}, dbName string) *Schedule {
+	if mongoClient, ok := cl.(*mongo.Client); ok {
+		collectionName := models.Schedule{}.TableName()
+		collection := mongoClient.Database(dbName).Collection(collectionName)
+
+		return &Schedule{db: collection}
+	} else {
+		return nil
+	}
+}
+
+// EnsureIndexes creates the indexes required by the schedule collection
+func (repo *Schedule) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "notification_id", Value: 1}},
+			Options: options.Index().SetName("schedule_notification_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "send_at", Value: 1}},
+			Options: options.Index().SetName("schedule_send_at"),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create schedule indexes: %v", err)
+	}
+	return nil
+}
+
+// Add holds a notification until schedule.SendAt. An existing schedule of the notification is
+// moved to the new send time and released. ErrScheduleRejected is returned when the database
+// rejects the schedule; other errors are transient.
+func (repo *Schedule) Add(ctx context.Context, schedule *models.Schedule) error {
+	now := time.Now()
+	filter := bson.M{"notification_id": schedule.NotificationID}
+	update := bson.M{
+		"$set": bson.M{
+			"organization_id": schedule.OrganizationID,
+			"send_at":         schedule.SendAt,
+			"updated_at":      now,
+		},
+		"$unset":       bson.M{"lease_owner": "", "lease_expires_at": ""},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	_, err := repo.db.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	// A duplicate key only means another upsert of the notification won the race
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) && len(writeErr.WriteErrors) > 0 && !mongo.IsDuplicateKeyError(err) {
+		logger.Log.Error(fmt.Sprintf("failed to schedule notification %s: %v", schedule.NotificationID.Hex(), err))
+		return ErrScheduleRejected
+	}
+	if err != nil {
+		errStr := fmt.Sprintf("failed to schedule notification %s: %v", schedule.NotificationID.Hex(), err)
+		logger.Log.Error(errStr)
+		return errors.New(errStr)
+	}
+	return nil
+}
+
+// ClaimDue leases the schedule with the earliest send time that is due and not leased by another
+// scheduler, for the given duration. It returns nil when no schedule is due.
+func (repo *Schedule) ClaimDue(ctx context.Context, owner string, lease time.Duration) (*models.Schedule, error) {
+	now := time.Now()
+	filter := bson.M{
+		"send_at":          bson.M{"$lte": now},
+		"lease_expires_at": bson.M{"$not": bson.M{"$gt": now}},
+	}
+	update := bson.M{"$set": bson.M{
+		"lease_owner":      owner,
+		"lease_expires_at": now.Add(lease),
+		"updated_at":       now,
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "send_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var schedule models.Schedule
+	if err := repo.db.FindOneAndUpdate(ctx, filter, update, opts).Decode(&schedule); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim due schedule: %v", err)
+	}
+	return &schedule, nil
+}
+
+// Reschedule moves the schedule of a notification to a new send time. ErrScheduleNotFound is
+// returned when the notification has no schedule or a scheduler is releasing it.
+func (repo *Schedule) Reschedule(ctx context.Context, notificationID primitive.ObjectID, sendAt time.Time) error {
+	now := time.Now()
+	filter := bson.M{
+		"notification_id":  notificationID,
+		"lease_expires_at": bson.M{"$not": bson.M{"$gt": now}},
+	}
+	update := bson.M{
+		"$set":   bson.M{"send_at": sendAt, "updated_at": now},
+		"$unset": bson.M{"lease_owner": "", "lease_expires_at": ""},
+	}
+	result, err := repo.db.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule notification %s: %v", notificationID.Hex(), err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+// Delete removes a schedule
+func (repo *Schedule) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if _, err := repo.db.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return fmt.Errorf("failed to delete schedule %s: %v", id.Hex(), err)
+	}
+	return nil
+}
+
+// DeleteByNotification removes the schedule of a notification, if any
+func (repo *Schedule) DeleteByNotification(ctx context.Context, notificationID primitive.ObjectID) error {
+	if _, err := repo.db.DeleteOne(ctx, bson.M{"notification_id": notificationID}); err != nil {
+		return fmt.Errorf("failed to delete schedule of notification %s: %v", notificationID.Hex(), err)
+	}
+	return nil
+}
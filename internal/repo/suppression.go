@@ -0,0 +1,204 @@
+/*
+repo/suppression.go
+Author: Akhil C
+Description: Repository for the list of suppressed recipients in MongoDB.
+*/
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrSuppressionNotFound is returned when no matching suppression exists
+var ErrSuppressionNotFound = errors.New("suppression not found")
+
+// Suppression handles interactions with the suppression collection
+type Suppression struct {
+	db *mongo.Collection
+}
+
+// NewSuppressionRepo initializes the suppression repository with a MongoDB collection
+func NewSuppressionRepo(cl interface{}, dbName string) *Suppression {
+	if mongoClient, ok := cl.(*mongo.Client); ok {
+		collectionName := models.Suppression{}.TableName()
+		collection := mongoClient.Database(dbName).Collection(collectionName)
+
+		return &Suppression{db: collection}
+	} else {
+		return nil
+	}
+}
+
+// EnsureIndexes creates the indexes required by the suppression collection: one suppression per
+// organization, channel and recipient, and a TTL index removing expired suppressions
+func (repo *Suppression) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "organization_id", Value: 1},
+				{Key: "channel", Value: 1},
+				{Key: "recipient", Value: 1},
+			},
+			Options: options.Index().SetName("suppression_recipient_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("suppression_expiry").SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create suppression indexes: %v", err)
+	}
+	return nil
+}
+
+// Add suppresses the recipient of suppression, replacing the reason, detail and expiry of an
+// existing suppression of the same recipient. The stored suppression is decoded into suppression.
+func (repo *Suppression) Add(ctx context.Context, suppression *models.Suppression) error {
+	now := time.Now()
+	suppression.Recipient = models.NormalizeRecipient(suppression.Recipient)
+
+	filter := bson.M{
+		"organization_id": organizationKey(suppression.OrganizationID),
+		"channel":         suppression.Channel,
+		"recipient":       suppression.Recipient,
+	}
+	set := bson.M{
+		"reason":     suppression.Reason,
+		"detail":     suppression.Detail,
+		"updated_at": now,
+	}
+	if !suppression.NotificationID.IsZero() {
+		set["notification_id"] = suppression.NotificationID
+	}
+	update := expiryUpdate(set, suppression.ExpiresAt)
+	update["$setOnInsert"] = bson.M{"created_at": now}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := repo.db.FindOneAndUpdate(ctx, filter, update, opts).Decode(suppression); err != nil {
+		errStr := fmt.Sprintf("failed to suppress %s recipient %s: %v", suppression.Channel, suppression.Recipient, err)
+		logger.Log.Error(errStr)
+		return errors.New(errStr)
+	}
+	return nil
+}
+
+// Find returns a suppression of an organization by ID
+func (repo *Suppression) Find(ctx context.Context, orgID, id primitive.ObjectID) (*models.Suppression, error) {
+	var suppression models.Suppression
+	if err := repo.db.FindOne(ctx, bson.M{"_id": id, "organization_id": orgID}).Decode(&suppression); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrSuppressionNotFound
+		}
+		return nil, err
+	}
+	return &suppression, nil
+}
+
+// List lists the suppressions of an organization, newest first. Empty channel and recipient match any.
+func (repo *Suppression) List(ctx context.Context, orgID primitive.ObjectID, channel, recipient string) ([]*models.Suppression, error) {
+	filter := bson.M{"organization_id": orgID}
+	if channel != "" {
+		filter["channel"] = channel
+	}
+	if recipient != "" {
+		filter["recipient"] = models.NormalizeRecipient(recipient)
+	}
+	cursor, err := repo.db.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	suppressions := []*models.Suppression{}
+	if err := cursor.All(ctx, &suppressions); err != nil {
+		return nil, err
+	}
+	return suppressions, nil
+}
+
+// Update changes the reason, detail and expiry of a suppression. The updated suppression is decoded into suppression.
+func (repo *Suppression) Update(ctx context.Context, suppression *models.Suppression) error {
+	set := bson.M{
+		"reason":     suppression.Reason,
+		"detail":     suppression.Detail,
+		"updated_at": time.Now(),
+	}
+	update := expiryUpdate(set, suppression.ExpiresAt)
+
+	filter := bson.M{"_id": suppression.ID, "organization_id": suppression.OrganizationID}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := repo.db.FindOneAndUpdate(ctx, filter, update, opts).Decode(suppression); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrSuppressionNotFound
+		}
+		return fmt.Errorf("failed to update suppression %s: %v", suppression.ID.Hex(), err)
+	}
+	return nil
+}
+
+// Delete lifts a suppression of an organization
+func (repo *Suppression) Delete(ctx context.Context, orgID, id primitive.ObjectID) error {
+	result, err := repo.db.DeleteOne(ctx, bson.M{"_id": id, "organization_id": orgID})
+	if err != nil {
+		return fmt.Errorf("failed to delete suppression %s: %v", id.Hex(), err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrSuppressionNotFound
+	}
+	return nil
+}
+
+// Active returns the suppression preventing notifications of channel from being sent to the
+// recipient on behalf of an organization, or nil when there is none. Only the suppressions of the
+// organization apply; expired suppressions are ignored.
+func (repo *Suppression) Active(ctx context.Context, orgID primitive.ObjectID, channel, recipient string) (*models.Suppression, error) {
+	filter := bson.M{
+		"organization_id": organizationKey(orgID),
+		"channel":         channel,
+		"recipient":       models.NormalizeRecipient(recipient),
+		// The TTL monitor only runs once a minute
+		"expires_at": bson.M{"$not": bson.M{"$lte": time.Now()}},
+	}
+	var suppression models.Suppression
+	if err := repo.db.FindOne(ctx, filter).Decode(&suppression); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to look up suppression: %v", err)
+	}
+	return &suppression, nil
+}
+
+// IsSuppressed reports whether notifications of channel must not be sent to recipient on behalf of an organization
+func (repo *Suppression) IsSuppressed(ctx context.Context, orgID primitive.ObjectID, channel, recipient string) (bool, error) {
+	suppression, err := repo.Active(ctx, orgID, channel, recipient)
+	return suppression != nil, err
+}
+
+// organizationKey returns the value matching the organization of a suppression; suppressions
+// without organization are stored with a null organization
+func organizationKey(orgID primitive.ObjectID) interface{} {
+	if orgID.IsZero() {
+		return nil
+	}
+	return orgID
+}
+
+// expiryUpdate returns an update applying set and setting expiresAt, or removing the expiry when it is nil
+func expiryUpdate(set bson.M, expiresAt *time.Time) bson.M {
+	if expiresAt == nil {
+		return bson.M{"$set": set, "$unset": bson.M{"expires_at": ""}}
+	}
+	set["expires_at"] = expiresAt
+	return bson.M{"$set": set}
+}
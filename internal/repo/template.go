@@ -0,0 +1,170 @@
+/*
+repo/template.go
+Author: Akhil C
+Description: Repository for managing versioned notification templates in MongoDB.
+*/
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrTemplateNotFound is returned when no matching template version exists
+var ErrTemplateNotFound = errors.New("template not found")
+
+// ErrTemplateVersionExists is returned when the template version has already been stored
+var ErrTemplateVersionExists = errors.New("template version already exists")
+
+// Template handles interactions with the template collection
+type Template struct {
+	db *mongo.Collection
+}
+
+// NewTemplateRepo initializes the template repository with a MongoDB collection
+func NewTemplateRepo(cl interface{}, dbName string) *Template {
+	if mongoClient, ok := cl.(*mongo.Client); ok {
+		collectionName := models.Template{}.TableName()
+		collection := mongoClient.Database(dbName).Collection(collectionName)
+
+		return &Template{db: collection}
+	} else {
+		return nil
+	}
+}
+
+// EnsureIndexes creates the indexes required by the template collection
+func (repo *Template) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "organization_id", Value: 1},
+			{Key: "template_id", Value: 1},
+			{Key: "version", Value: -1},
+		},
+		Options: options.Index().SetName("template_version_unique").SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create template indexes: %v", err)
+	}
+	return nil
+}
+
+// CreateVersion stores a new template version and assigns the generated ID to it
+func (repo *Template) CreateVersion(ctx context.Context, template *models.Template) error {
+	now := time.Now()
+	template.CreatedAt = now
+	template.UpdatedAt = now
+	result, err := repo.db.InsertOne(ctx, template)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrTemplateVersionExists
+	}
+	if err != nil {
+		errStr := fmt.Sprintf("failed to store template: %v", err)
+		logger.Log.Error(errStr)
+		return errors.New(errStr)
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		template.ID = id
+	}
+	return nil
+}
+
+// FindVersion returns the given version of an active template, or the latest version when version is 0
+func (repo *Template) FindVersion(ctx context.Context, orgID primitive.ObjectID, templateID string, version int) (*models.Template, error) {
+	filter := bson.M{
+		"organization_id": orgID,
+		"template_id":     templateID,
+		"active":          true,
+	}
+	if version > 0 {
+		filter["version"] = version
+	}
+
+	var template models.Template
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+	if err := repo.db.FindOne(ctx, filter, opts).Decode(&template); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// MaxVersion returns the highest version number stored for a template, including deleted
+// versions, or 0 when the template has never been stored
+func (repo *Template) MaxVersion(ctx context.Context, orgID primitive.ObjectID, templateID string) (int, error) {
+	var template models.Template
+	filter := bson.M{"organization_id": orgID, "template_id": templateID}
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}}).SetProjection(bson.M{"version": 1})
+	if err := repo.db.FindOne(ctx, filter, opts).Decode(&template); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return template.Version, nil
+}
+
+// ListVersions lists every version of an active template, newest first
+func (repo *Template) ListVersions(ctx context.Context, orgID primitive.ObjectID, templateID string) ([]*models.Template, error) {
+	filter := bson.M{
+		"organization_id": orgID,
+		"template_id":     templateID,
+		"active":          true,
+	}
+	cursor, err := repo.db.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "version", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	var templates []*models.Template
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// ListLatest lists the latest version of every active template of an organization
+func (repo *Template) ListLatest(ctx context.Context, orgID primitive.ObjectID) ([]*models.Template, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"organization_id": orgID, "active": true}}},
+		{{Key: "$sort", Value: bson.D{{Key: "template_id", Value: 1}, {Key: "version", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$template_id", "latest": bson.M{"$first": "$$ROOT"}}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$latest"}}},
+		{{Key: "$sort", Value: bson.D{{Key: "template_id", Value: 1}}}},
+	}
+	cursor, err := repo.db.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var templates []*models.Template
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// Deactivate marks every version of a template as deleted
+func (repo *Template) Deactivate(ctx context.Context, orgID primitive.ObjectID, templateID string) error {
+	result, err := repo.db.UpdateMany(ctx,
+		bson.M{"organization_id": orgID, "template_id": templateID, "active": true},
+		bson.M{"$set": bson.M{"active": false, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete template %s: %v", templateID, err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+repo/whatsappSession.go
+Author: Akhil C
+Description: Repository for the WhatsApp session window of each recipient in MongoDB.
+*/
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WhatsAppSession handles interactions with the WhatsApp session collection
+type WhatsAppSession struct {
+	db *mongo.Collection
+}
+
+// NewWhatsAppSessionRepo initializes the WhatsApp session repository with a MongoDB collection
+func NewWhatsAppSessionRepo(cl interface{}, dbName string) *WhatsAppSession {
+	if mongoClient, ok := cl.(*mongo.Client); ok {
+		collectionName := models.WhatsAppSession{}.TableName()
+		collection := mongoClient.Database(dbName).Collection(collectionName)
+
+		return &WhatsAppSession{db: collection}
+	} else {
+		return nil
+	}
+}
+
+// EnsureIndexes creates a TTL index removing sessions once their window has closed
+func (repo *WhatsAppSession) EnsureIndexes(ctx context.Context) error {
+	_, err := repo.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetName("whatsapp_session_ttl").SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create whatsapp session indexes: %v", err)
+	}
+	return nil
+}
+
+// RecordInbound opens or extends the session window of recipient from a message received at the given time
+func (repo *WhatsAppSession) RecordInbound(ctx context.Context, recipient string, at time.Time) error {
+	// Webhooks may arrive out of order: only move the window forward
+	filter := bson.M{"_id": sessionKey(recipient), "last_inbound_at": bson.M{"$lt": at}}
+	update := bson.M{"$set": bson.M{
+		"last_inbound_at": at,
+		"expires_at":      at.Add(models.WhatsAppSessionWindow),
+	}}
+	_, err := repo.db.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil // A newer inbound message is already recorded
+	}
+	if err != nil {
+		errStr := fmt.Sprintf("failed to record whatsapp session: %v", err)
+		logger.Log.Error(errStr)
+		return errors.New(errStr)
+	}
+	return nil
+}
+
+// SessionOpen reports whether recipient sent a message within the session window
+func (repo *WhatsAppSession) SessionOpen(ctx context.Context, recipient string) (bool, error) {
+	var session models.WhatsAppSession
+	err := repo.db.FindOne(ctx, bson.M{"_id": sessionKey(recipient)}).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to find whatsapp session: %v", err)
+	}
+	// The TTL monitor only runs periodically, so expired documents may still be present
+	return time.Now().Before(session.ExpiresAt), nil
+}
+
+// sessionKey normalizes a phone number ("whatsapp:+155...", "155...") to E.164 with a leading '+'
+func sessionKey(number string) string {
+	number = strings.TrimPrefix(strings.TrimSpace(number), "whatsapp:")
+	return "+" + strings.TrimPrefix(number, "+")
+}
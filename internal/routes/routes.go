@@ -7,8 +7,15 @@ Description: This file defines the main router configuration for the Fiber web a
 package routers
 
 import (
+	"context"
+	"fmt"
+	"sync"
+
 	"github.com/akhilckenshi/notification/internal/controller"
 	"github.com/akhilckenshi/notification/internal/database"
+	"github.com/akhilckenshi/notification/internal/dispatcher"
+	"github.com/akhilckenshi/notification/internal/notifications"
+	"github.com/akhilckenshi/notification/internal/ratelimit"
 	"github.com/akhilckenshi/notification/internal/repo"
 	"github.com/akhilckenshi/notification/internal/service"
 	"github.com/akhilckenshi/notification/pkg/logger"
@@ -17,26 +24,34 @@ import (
 )
 
 // GetRouter initializes and returns the main Fiber application with configured routes.
-func GetRouter() *fiber.App {
+// Background workers started for the routes (e.g. the Kafka consumer) stop when ctx is cancelled;
+// background is done once they have stopped and released their resources.
+func GetRouter(ctx context.Context, background *sync.WaitGroup) *fiber.App {
 	app := fiber.New() // Initialize a new Fiber app
 
 	// Create an API group for versioning or common routes..
 	api := app.Group("/api")
 
 	// Setup API version 1 (v1) routes..
-	getV1ApiList(api)
+	getV1ApiList(ctx, background, api)
 
 	return app // Return the configured Fiber app..
 }
 
 // getV1ApiList sets up the version 1 (v1) API routes under the /api/v1 group.
-func getV1ApiList(api fiber.Router) {
+func getV1ApiList(ctx context.Context, background *sync.WaitGroup, api fiber.Router) {
 	// Group v1 routes under /api/v1..
 	v1 := api.Group("/v1")
 
 	// Initialize repositories and services..
 	// Get the database client and database name..
 	var notificationRepo *repo.Notification
+	var templateRepo *repo.Template
+	var sessionRepo *repo.WhatsAppSession
+	var suppressionRepo *repo.Suppression
+	var preferenceRepo *repo.Preference
+	var scheduleRepo *repo.Schedule
+	var quietHoursRepo *repo.QuietHours
 
 	dbClient := database.GetDBClient()
 	dbName := database.GetDBName()
@@ -45,28 +60,232 @@ func getV1ApiList(api fiber.Router) {
 	if mongoClient, ok := dbClient.(*mongo.Client); ok {
 		// MongoDB client.
 		notificationRepo = repo.NewNotificationRepo(mongoClient, dbName)
+		if err := notificationRepo.EnsureIndexes(ctx); err != nil {
+			logger.Log.Error(err.Error())
+		}
+		templateRepo = repo.NewTemplateRepo(mongoClient, dbName)
+		if err := templateRepo.EnsureIndexes(ctx); err != nil {
+			logger.Log.Error(err.Error())
+		}
+		sessionRepo = repo.NewWhatsAppSessionRepo(mongoClient, dbName)
+		if err := sessionRepo.EnsureIndexes(ctx); err != nil {
+			logger.Log.Error(err.Error())
+		}
+		suppressionRepo = repo.NewSuppressionRepo(mongoClient, dbName)
+		if err := suppressionRepo.EnsureIndexes(ctx); err != nil {
+			logger.Log.Error(err.Error())
+		}
+		preferenceRepo = repo.NewPreferenceRepo(mongoClient, dbName)
+		if err := preferenceRepo.EnsureIndexes(ctx); err != nil {
+			logger.Log.Error(err.Error())
+		}
+		scheduleRepo = repo.NewScheduleRepo(mongoClient, dbName)
+		if err := scheduleRepo.EnsureIndexes(ctx); err != nil {
+			logger.Log.Error(err.Error())
+		}
+		quietHoursRepo = repo.NewQuietHoursRepo(mongoClient, dbName)
+		if err := quietHoursRepo.EnsureIndexes(ctx); err != nil {
+			logger.Log.Error(err.Error())
+		}
 
 	} else {
 		// No database client available, log an error.
 		logger.Log.Error("No DB Client available")
 	}
 
+	// Setup routes for Template APIs.
+	templateService := getTemplateApi(v1, templateRepo)
+
 	// Setup routes for Notification APIs.
-	getNotificationApi(v1, notificationRepo)
+	getNotificationApi(ctx, background, v1, notificationRepo, templateService, sessionRepo, suppressionRepo, preferenceRepo, scheduleRepo, quietHoursRepo)
+
+	// Setup routes for WhatsApp webhooks.
+	getWhatsAppApi(v1, sessionRepo)
+
+	// Setup routes for Suppression APIs.
+	getSuppressionApi(v1, suppressionRepo)
+
+	// Setup routes for Preference APIs.
+	getPreferenceApi(v1, preferenceRepo)
+
+	// Setup routes for Quiet Hours APIs.
+	getQuietHoursApi(v1, quietHoursRepo)
+}
+
+// getTemplateApi sets up the Template-related routes under /templates and returns the
+// template service so it can be shared with the notification service.
+func getTemplateApi(v fiber.Router, templateRepo *repo.Template) *service.TemplateService {
+	// Initialize Template service and controller.
+	templateService := service.NewTemplateService(templateRepo)
+	templateController := controller.NewTemplateController(templateService)
+
+	// Define routes for Template-related actions (Create, Get, Update, Delete, Preview).
+	tpl := v.Group("/templates")
+
+	// Template routes
+	tpl.Post("/", templateController.CreateTemplate)                          // Route to create a new template.
+	tpl.Get("/", templateController.ReadAllTemplates)                         // Route to list the latest version of all templates.
+	tpl.Get("/:templateId", templateController.ReadTemplate)                  // Route to retrieve a template (latest or ?version=).
+	tpl.Get("/:templateId/versions", templateController.ReadTemplateVersions) // Route to list all versions of a template.
+	tpl.Put("/:templateId", templateController.UpdateTemplate)                // Route to store a new version of a template.
+	tpl.Delete("/:templateId", templateController.DeleteTemplate)             // Route to delete a template.
+	tpl.Post("/:templateId/preview", templateController.PreviewTemplate)      // Route to render a template without sending it.
+
+	return templateService
 }
 
 // getNotificationApi sets up the Notification-related routes under /Account.
-func getNotificationApi(v fiber.Router, notificationRepo *repo.Notification) {
+func getNotificationApi(ctx context.Context, background *sync.WaitGroup, v fiber.Router, notificationRepo *repo.Notification, templateService *service.TemplateService, sessionRepo *repo.WhatsAppSession, suppressionRepo *repo.Suppression, preferenceRepo *repo.Preference, scheduleRepo *repo.Schedule, quietHoursRepo *repo.QuietHours) {
+	// Register the delivery channels available to the service.
+	// Emails are sent through the configured providers, which are closed on shutdown,
+	// and never to addresses suppressed after a bounce or complaint.
+	// Providers, channels and organizations are rate limited as configured under rateLimits.
+	limiter := ratelimit.NewFromConfig()
+	emailProviders, err := notifications.NewEmailProvidersFromConfig()
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Invalid email provider configuration: %v", err))
+	}
+	for i, provider := range emailProviders {
+		emailProviders[i] = notifications.LimitEmailProvider(provider, limiter)
+	}
+	var suppressions notifications.SuppressionList
+	if suppressionRepo != nil {
+		suppressions = suppressionRepo
+	}
+	emailNotifier := notifications.NewEmailNotifier(suppressions, emailProviders...)
+
+	registry := notifications.NewRegistry()
+	registry.Register(notifications.TypeEmail, emailNotifier)
+	// The WhatsApp channel picks between free-form and template messages using the session windows.
+	var sessions notifications.SessionStore
+	if sessionRepo != nil {
+		sessions = sessionRepo
+	}
+	// SMS and WhatsApp messages are sent through the same Twilio account and share its rate limit.
+	registry.Register(notifications.TypeWhatsApp, notifications.LimitNotifier(notifications.NewWhatsAppNotifier(sessions), "twilio", limiter))
+	registry.Register(notifications.TypeSMS, notifications.LimitNotifier(notifications.NewSMSNotifier(), "twilio", limiter))
+
+	// Dead-letter producer for notifications that cannot be delivered (optional).
+	deadLetter, err := service.NewDeadLetterProducerFromConfig()
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Dead-letter topic disabled: %v", err))
+	}
+
+	// Priority queues notifications are sent from, served by workers until shutdown.
+	notificationDispatcher := dispatcher.NewFromConfig()
+	notificationDispatcher.Start(ctx)
+
 	// Initialize Notification service and controller.
-	notificationService := service.NewNotificationService(notificationRepo)
+	notificationService := service.NewNotificationService(notificationRepo, registry, templateService, deadLetter, notificationDispatcher, limiter, suppressionRepo, preferenceRepo, scheduleRepo, quietHoursRepo)
 	notificationController := controller.NewNotificationController(notificationService)
 
-	// Concurrently execute the messageConsumer
-	go notificationService.MessageConsumer()
+	// Concurrently execute the messageConsumer and the scheduler releasing scheduled notifications.
+	// The providers and the dead-letter producer are closed once nothing sends through them anymore.
+	notificationService.Start(ctx)
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		notificationService.Wait()
+		emailNotifier.Close()
+		if deadLetter != nil {
+			deadLetter.Close()
+		}
+	}()
 
-	// Define routes for Notification-related actions (Get).
+	// Define routes for Notification-related actions (Get, Create, Batch create).
 	doc := v.Group("/notification")
 
 	// Notification routes
-	doc.Get("/", notificationController.ReadAllNotifications) // Route to retrieve all notifications from the system.
+	doc.Get("/", notificationController.ReadAllNotifications)                           // Route to retrieve all notifications from the system.
+	doc.Post("/", notificationController.CreateNotification)                            // Route to submit a notification for delivery.
+	doc.Post("/batch", notificationController.CreateNotificationBatch)                  // Route to submit several notifications at once.
+	doc.Post("/:notificationId/cancel", notificationController.CancelNotification)      // Route to cancel a scheduled notification.
+	doc.Put("/:notificationId/schedule", notificationController.RescheduleNotification) // Route to move a scheduled notification to another send time.
+
+	// Define routes for provider webhooks reporting the delivery status of sent notifications.
+	webhookController := controller.NewWebhookController(notificationService)
+	hooks := v.Group("/webhooks")
+
+	// Webhook routes
+	hooks.Post("/twilio/status", controller.TwilioSignature(), webhookController.TwilioStatus) // Route receiving Twilio message status callbacks.
+	hooks.Post("/email/bounce", controller.WebhookToken(), webhookController.EmailBounce)      // Route receiving bounce and complaint events as JSON.
+	hooks.Post("/email/dsn", controller.WebhookToken(), webhookController.EmailDSN)            // Route receiving raw bounce (RFC 3464) and complaint (ARF) emails.
+
+	// Define routes reporting the state of the delivery pipeline.
+	metricsController := controller.NewMetricsController(notificationService)
+	metrics := v.Group("/metrics")
+
+	// Metrics routes
+	metrics.Get("/rate-limits", metricsController.RateLimits) // Route reporting the utilization of the rate limits.
+}
+
+// getWhatsAppApi sets up the WhatsApp webhook routes under /whatsapp.
+func getWhatsAppApi(v fiber.Router, sessionRepo *repo.WhatsAppSession) {
+	// Initialize WhatsApp service and controller.
+	whatsAppService := service.NewWhatsAppService(sessionRepo)
+	whatsAppController := controller.NewWhatsAppController(whatsAppService)
+
+	// Define routes for WhatsApp webhooks.
+	wa := v.Group("/whatsapp")
+
+	// WhatsApp routes
+	wa.Post("/inbound", controller.TwilioSignature(), whatsAppController.Inbound) // Route receiving inbound messages from Twilio.
+}
+
+// getSuppressionApi sets up the Suppression-related routes under /suppressions.
+func getSuppressionApi(v fiber.Router, suppressionRepo *repo.Suppression) {
+	// Initialize Suppression service and controller.
+	suppressionService := service.NewSuppressionService(suppressionRepo)
+	suppressionController := controller.NewSuppressionController(suppressionService)
+
+	// Define routes for Suppression-related actions (Create, Get, Update, Delete).
+	sup := v.Group("/suppressions")
+
+	// Suppression routes
+	sup.Post("/", suppressionController.CreateSuppression)                 // Route to suppress a recipient.
+	sup.Get("/", suppressionController.ReadAllSuppressions)                // Route to list the suppressions of an organization.
+	sup.Get("/:suppressionId", suppressionController.ReadSuppression)      // Route to retrieve a suppression.
+	sup.Put("/:suppressionId", suppressionController.UpdateSuppression)    // Route to change the reason or expiry of a suppression.
+	sup.Delete("/:suppressionId", suppressionController.DeleteSuppression) // Route to lift a suppression.
+}
+
+// getPreferenceApi sets up the Preference-related routes under /preferences and the unsubscribe routes under /unsubscribe.
+func getPreferenceApi(v fiber.Router, preferenceRepo *repo.Preference) {
+	// Initialize Preference service and controller.
+	preferenceService := service.NewPreferenceService(preferenceRepo)
+	preferenceController := controller.NewPreferenceController(preferenceService)
+
+	// Define routes for Preference-related actions (Get, Update, Delete).
+	pref := v.Group("/preferences")
+
+	// Preference routes
+	pref.Get("/", preferenceController.ReadAllPreferences)            // Route to list the preferences of the recipients of an organization.
+	pref.Get("/:recipient", preferenceController.ReadPreference)      // Route to retrieve the preferences of a recipient.
+	pref.Put("/:recipient", preferenceController.UpdatePreference)    // Route to store the preferences of a recipient.
+	pref.Delete("/:recipient", preferenceController.DeletePreference) // Route to remove the preferences of a recipient.
+
+	// Define the public routes of the unsubscribe links of marketing emails.
+	unsubscribeController := controller.NewUnsubscribeController(preferenceService)
+	unsub := v.Group("/unsubscribe")
+
+	// Unsubscribe routes
+	unsub.Get("/", unsubscribeController.ConfirmUnsubscribe) // Route showing the unsubscribe confirmation page.
+	unsub.Post("/", unsubscribeController.Unsubscribe)       // Route receiving one-click unsubscribe requests.
+}
+
+// getQuietHoursApi sets up the Quiet Hours-related routes under /quiet-hours.
+func getQuietHoursApi(v fiber.Router, quietHoursRepo *repo.QuietHours) {
+	// Initialize Quiet Hours service and controller.
+	quietHoursService := service.NewQuietHoursService(quietHoursRepo)
+	quietHoursController := controller.NewQuietHoursController(quietHoursService)
+
+	// Define routes for Quiet Hours-related actions (Get, Update, Delete).
+	quiet := v.Group("/quiet-hours")
+
+	// Quiet Hours routes
+	quiet.Get("/", quietHoursController.ReadAllQuietHours)             // Route to list the quiet hours of an organization and its recipients.
+	quiet.Put("/", quietHoursController.UpdateQuietHours)              // Route to store the quiet hours of an organization.
+	quiet.Delete("/", quietHoursController.DeleteQuietHours)           // Route to remove the quiet hours of an organization.
+	quiet.Put("/:recipient", quietHoursController.UpdateQuietHours)    // Route to store the quiet hours of a recipient.
+	quiet.Delete("/:recipient", quietHoursController.DeleteQuietHours) // Route to remove the quiet hours of a recipient.
 }
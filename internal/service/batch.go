@@ -0,0 +1,147 @@
+/*
+service/batch.go
+Author: Akhil C
+Description: Batch submission of notifications with per-item validation and results.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/repo"
+	config "github.com/akhilckenshi/notification/pkg/settings"
+)
+
+const defaultMaxBatchSize = 1000 // Batch size limit used when app.maxBatchSize is not set
+
+// BatchTooLargeError is returned when a batch exceeds the configured maximum size
+type BatchTooLargeError struct {
+	Size int
+	Max  int
+}
+
+func (e *BatchTooLargeError) Error() string {
+	return fmt.Sprintf("batch of %d notifications exceeds the maximum of %d", e.Size, e.Max)
+}
+
+// BatchItemResult is the outcome of a single notification of a batch
+type BatchItemResult struct {
+	Index  int    `json:"index"`            // Position of the item in the submitted batch
+	ID     string `json:"id,omitempty"`     // ID of the created notification
+	Status string `json:"status,omitempty"` // Status of the created notification
+	Error  string `json:"error,omitempty"`  // Reason the item was rejected
+	// Set when the idempotency key was already used; ID and Status then refer to the original notification
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
+// setDuplicate reports the original notification of a duplicate item
+func (r *BatchItemResult) setDuplicate(original *models.Notification) {
+	r.ID = original.ID.Hex()
+	r.Status = original.Status
+	r.Duplicate = true
+}
+
+// maxBatchSize returns the configured batch size limit
+func maxBatchSize() int {
+	if config.Config.App.MaxBatchSize > 0 {
+		return config.Config.App.MaxBatchSize
+	}
+	return defaultMaxBatchSize
+}
+
+// SubmitNotificationBatch validates and stores every notification of a batch and dispatches
+// the accepted ones in the background. Invalid or unstored items do not affect the others;
+// the returned results are in input order. Items with an idempotency key that was already
+// used are reported as duplicates of the original notification and are not sent again. A *BatchTooLargeError is returned when the batch
+// exceeds the configured maximum size.
+func (s *NotificationService) SubmitNotificationBatch(ctx context.Context, notifiers []*models.Notifier) ([]BatchItemResult, error) {
+	if max := maxBatchSize(); len(notifiers) > max {
+		return nil, &BatchTooLargeError{Size: len(notifiers), Max: max}
+	}
+
+	results := make([]BatchItemResult, len(notifiers))
+	var accepted []*models.Notification
+	var acceptedIndexes []int
+	for i, notifier := range notifiers {
+		results[i].Index = i
+		if notifier == nil {
+			results[i].Error = "notification is required"
+			continue
+		}
+		if err := s.validateNotifier(ctx, notifier); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		accepted = append(accepted, newNotification(notifier))
+		acceptedIndexes = append(acceptedIndexes, i)
+	}
+
+	// Items whose idempotency key was already used are answered with the original notification
+	originals, err := s.lookupDuplicates(ctx, accepted)
+	if err != nil {
+		return nil, err
+	}
+	var pending []*models.Notification
+	var pendingIndexes []int
+	for i, msg := range accepted {
+		if original, ok := originals[msg.IdempotencyKey]; ok && msg.IdempotencyKey != "" {
+			results[acceptedIndexes[i]].setDuplicate(original)
+			continue
+		}
+		pending = append(pending, msg)
+		pendingIndexes = append(pendingIndexes, acceptedIndexes[i])
+	}
+
+	// Store the remaining notifications in a single round trip
+	errs := s.repo.StoreNotifications(ctx, pending)
+	var stored []*models.Notification
+	var conflicts []int
+	for i, msg := range pending {
+		result := &results[pendingIndexes[i]]
+		switch {
+		case errors.Is(errs[i], repo.ErrDuplicateNotification):
+			// Key repeated within the batch or stored concurrently by another instance
+			conflicts = append(conflicts, i)
+		case errs[i] != nil:
+			result.Error = errs[i].Error()
+		default:
+			result.ID = msg.ID.Hex()
+			result.Status = msg.Status
+			stored = append(stored, msg)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		keys := make([]string, len(conflicts))
+		for i, conflict := range conflicts {
+			keys[i] = pending[conflict].IdempotencyKey
+		}
+		originals, err := s.repo.FindByIdempotencyKeys(ctx, keys)
+		for _, conflict := range conflicts {
+			result := &results[pendingIndexes[conflict]]
+			if original, ok := originals[pending[conflict].IdempotencyKey]; err == nil && ok {
+				result.setDuplicate(original)
+			} else {
+				result.Error = repo.ErrDuplicateNotification.Error()
+			}
+		}
+	}
+
+	// The request context ends with the HTTP call, so delivery runs on the context of the service
+	s.goBackground(func() { s.dispatchAll(s.ctx, stored) })
+
+	return results, nil
+}
+
+// dispatchAll queues the given notifications on the dispatcher, which sends them according to their
+// priority. A notification that cannot be queued is logged by enqueue and stays queued in the
+// repository, where the next recovery sweep finds it; the others are still queued.
+func (s *NotificationService) dispatchAll(ctx context.Context, msgs []*models.Notification) {
+	for _, msg := range msgs {
+		s.enqueue(ctx, msg, nil, nil)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+service/bounce.go
+Author: Akhil C
+Description: Applies email bounces and complaints: marks the original notification failed and suppresses the address.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/akhilckenshi/notification/internal/bounce"
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/notifications"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// HandleBounces applies bounce and complaint events and returns the number of addresses suppressed.
+// Hard bounces fail the original notification when it was sent to the bounced address; hard
+// bounces and complaints add the address to the suppression list of the organization that sent
+// it. Soft bounces are only recorded. Events are validated before any is applied, so a rejected
+// batch can be sent again as a whole.
+func (s *NotificationService) HandleBounces(ctx context.Context, events []bounce.Event) (int, error) {
+	for i, event := range events {
+		if err := validateBounce(event); err != nil {
+			err.Field = fmt.Sprintf("events[%d].%s", i, err.Field) // Names the rejected event of the batch
+			return 0, err
+		}
+	}
+
+	suppressed := 0
+	for _, event := range events {
+		msg, err := s.findBouncedNotification(ctx, event.MessageID)
+		if err != nil {
+			return suppressed, err
+		}
+		if msg != nil {
+			if err := s.recordBounce(ctx, msg, event); err != nil {
+				return suppressed, err
+			}
+		}
+
+		if !event.Permanent() || s.suppressions == nil {
+			continue
+		}
+		// Suppressions belong to an organization; without the original message there is none to manage it
+		if msg == nil {
+			logger.Log.Warn(fmt.Sprintf("Not suppressing %s after %s of unknown message %q", event.Recipient, event.Type, event.MessageID))
+			continue
+		}
+		suppression := &models.Suppression{
+			OrganizationID: msg.OrganizationID,
+			NotificationID: msg.ID,
+			Channel:        notifications.TypeEmail,
+			Recipient:      event.Recipient,
+			Reason:         models.SuppressionBounce,
+			Detail:         event.Diagnostic,
+		}
+		if event.Type == bounce.TypeComplaint {
+			suppression.Reason = models.SuppressionComplaint
+		}
+		if err := s.suppressions.Add(ctx, suppression); err != nil {
+			return suppressed, err
+		}
+		suppressed++
+		logger.Log.Info(fmt.Sprintf("Suppressed %s after %s: %s %s", event.Recipient, event.Type, event.Status, event.Diagnostic))
+	}
+	return suppressed, nil
+}
+
+// validateBounce checks the type and recipient of a bounce or complaint event
+func validateBounce(event bounce.Event) *ValidationError {
+	if event.Type != bounce.TypeBounce && event.Type != bounce.TypeComplaint {
+		return &ValidationError{Field: "type", Message: "must be bounce or complaint"}
+	}
+	if _, err := mail.ParseAddress(event.Recipient); err != nil {
+		return &ValidationError{Field: "recipient", Message: "must be a valid email address"}
+	}
+	return nil
+}
+
+// findBouncedNotification returns the notification sent with the given Message-ID or provider
+// message ID, or nil when it is unknown
+func (s *NotificationService) findBouncedNotification(ctx context.Context, messageID string) (*models.Notification, error) {
+	if messageID == "" {
+		return nil, nil
+	}
+	// Message-IDs are stored with angle brackets; reports do not always keep them
+	candidates := []string{messageID}
+	if !strings.HasPrefix(messageID, "<") {
+		candidates = append(candidates, "<"+messageID+">")
+	}
+	for _, candidate := range candidates {
+		msg, err := s.repo.FindByProviderMessageID(ctx, candidate)
+		if err == nil {
+			return msg, nil
+		}
+		if !errors.Is(err, repo.ErrNotificationNotFound) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// recordBounce stores the outcome of a bounce or complaint on the original notification
+func (s *NotificationService) recordBounce(ctx context.Context, msg *models.Notification, event bounce.Event) error {
+	fields := bson.M{"provider_error": event.Diagnostic}
+	switch {
+	case event.Type == bounce.TypeComplaint:
+		fields["provider_status"] = "complained"
+	case event.Permanent():
+		fields["provider_status"] = "bounced"
+	default:
+		fields["provider_status"] = "deferred"
+	}
+
+	// Only a hard bounce of the primary recipient means the notification was not delivered
+	sentTo := msg.To
+	if address, err := mail.ParseAddress(msg.To); err == nil {
+		sentTo = address.Address
+	}
+	if event.Type != bounce.TypeBounce || !event.Permanent() || !strings.EqualFold(sentTo, event.Recipient) ||
+		!models.CanTransition(msg.Status, models.StatusFailed) {
+		return s.repo.UpdateFields(ctx, msg.ID, fields)
+	}
+
+	reason := fmt.Sprintf("hard bounce %s: %s", event.Status, event.Diagnostic)
+	err := s.transition(ctx, msg, models.StatusFailed, reason, fields)
+	if errors.Is(err, repo.ErrStaleStatus) {
+		return s.repo.UpdateFields(ctx, msg.ID, fields)
+	}
+	return err
+}
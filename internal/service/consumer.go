@@ -0,0 +1,284 @@
+/*
+service/consumer.go
+Author: Akhil C
+Description: Kafka consumer group used to read notification messages from every partition of the notification topic.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akhilckenshi/notification/pkg/logger"
+	config "github.com/akhilckenshi/notification/pkg/settings"
+
+	"github.com/IBM/sarama"
+)
+
+const (
+	defaultConsumerGroupID = "notification-service" // Consumer group used when KAFKA_GROUP_ID is not set
+	consumerRetryDelay     = 5 * time.Second        // Delay before re-joining the group after a failure
+
+	// Time given to the messages in flight to finish when a partition is released, well within the
+	// rebalance timeout
+	partitionReleaseTimeout = 5 * time.Second
+)
+
+// kafkaBrokers returns the broker list configured in KAFKA_PORT (comma separated)
+func kafkaBrokers() []string {
+	var brokers []string
+	for _, broker := range strings.Split(config.Config.KafkaPort, ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	return brokers
+}
+
+// newConsumerConfig builds the sarama configuration for the notification consumer group
+func newConsumerConfig() (*sarama.Config, error) {
+	kafkaConf := config.Config.Kafka
+	configs := sarama.NewConfig()
+	configs.Consumer.Return.Errors = true
+	configs.Consumer.Offsets.AutoCommit.Enable = true
+
+	// Offset used when the group has not committed anything for a partition yet
+	switch strings.ToLower(kafkaConf.InitialOffset) {
+	case "", "oldest":
+		configs.Consumer.Offsets.Initial = sarama.OffsetOldest
+	case "newest":
+		configs.Consumer.Offsets.Initial = sarama.OffsetNewest
+	default:
+		return nil, fmt.Errorf("unsupported kafka initial offset: %s", kafkaConf.InitialOffset)
+	}
+
+	// Partition assignment strategy used when members join or leave the group
+	switch strings.ToLower(kafkaConf.RebalanceStrategy) {
+	case "", "sticky":
+		configs.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategySticky()}
+	case "roundrobin":
+		configs.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRoundRobin()}
+	case "range":
+		configs.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRange()}
+	default:
+		return nil, fmt.Errorf("unsupported kafka rebalance strategy: %s", kafkaConf.RebalanceStrategy)
+	}
+
+	if kafkaConf.SessionTimeout > 0 {
+		configs.Consumer.Group.Session.Timeout = time.Duration(kafkaConf.SessionTimeout) * time.Second
+	}
+	if kafkaConf.CommitInterval > 0 {
+		configs.Consumer.Offsets.AutoCommit.Interval = time.Duration(kafkaConf.CommitInterval) * time.Second
+	}
+	return configs, nil
+}
+
+// MessageConsumer joins the notification consumer group and processes messages
+// from every partition assigned to this instance until ctx is cancelled.
+func (s *NotificationService) MessageConsumer(ctx context.Context) {
+	configs, err := newConsumerConfig()
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Invalid Kafka consumer configuration: %v", err))
+		return
+	}
+
+	groupID := config.Config.KafkaGroupID
+	if groupID == "" {
+		groupID = defaultConsumerGroupID
+	}
+
+	group, err := sarama.NewConsumerGroup(kafkaBrokers(), groupID, configs)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Error creating Kafka consumer group: %v", err))
+		return
+	}
+	defer func() {
+		if err := group.Close(); err != nil {
+			logger.Log.Error(fmt.Sprintf("Error closing Kafka consumer group: %v", err))
+		}
+	}()
+
+	// Drain consumer errors so the group never blocks on them
+	go func() {
+		for err := range group.Errors() {
+			logger.Log.Error(fmt.Sprintf("Kafka consumer error: %v", err))
+		}
+	}()
+
+	logger.Log.Info(fmt.Sprintf("Kafka consumer group %s started on topic %s", groupID, config.Config.KafkaTopic))
+	handler := &consumerGroupHandler{service: s, group: group, ctx: ctx}
+	for {
+		// Consume blocks for the lifetime of a group session and returns on every rebalance,
+		// so it has to be called again to rejoin the group with the new assignment.
+		if err := group.Consume(ctx, []string{config.Config.KafkaTopic}, handler); err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				return
+			}
+			logger.Log.Error(fmt.Sprintf("Kafka consumer group session failed: %v", err))
+			select {
+			case <-ctx.Done():
+			case <-time.After(consumerRetryDelay):
+			}
+		}
+		if ctx.Err() != nil {
+			logger.Log.Info("Kafka consumer stopped")
+			return
+		}
+	}
+}
+
+// consumerGroupHandler implements sarama.ConsumerGroupHandler for notification messages
+type consumerGroupHandler struct {
+	service *NotificationService
+	group   sarama.ConsumerGroup
+	ctx     context.Context // Context of the consumer, outliving group sessions, that notifications are sent with
+
+	mu      sync.Mutex
+	waiting int // Partitions waiting for room in the send pool; fetching is paused while there are any
+}
+
+// Setup is run at the beginning of a new session, after partitions have been assigned
+func (h *consumerGroupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	logger.Log.Info(fmt.Sprintf("Kafka partitions assigned (generation %d): %v", session.GenerationID(), session.Claims()))
+	return nil
+}
+
+// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited
+// and before the final offsets are committed.
+func (h *consumerGroupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	logger.Log.Info(fmt.Sprintf("Kafka partitions released (generation %d)", session.GenerationID()))
+	return nil
+}
+
+// ConsumeClaim queues the messages of a single partition for sending, without waiting for them
+// to be sent; the send pool bounds how many are in flight. The offset of a message is only marked
+// once it and every earlier message of the partition have been handled, so a restart or rebalance
+// resumes after the last processed message instead of skipping work.
+func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	offsets := &offsetTracker{session: session}
+	// Messages in flight get a short time to finish before the partition is released, so their
+	// offsets are committed. The session ends with the rebalance, so the wait outlives it; messages
+	// still in flight are consumed again by the next owner of the partition and their notifications
+	// are still sent once, by the worker that leases them.
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(session.Context()), partitionReleaseTimeout)
+		defer cancel()
+		stop := context.AfterFunc(h.ctx, cancel) // Shutdown does not wait
+		defer stop()
+		offsets.wait(ctx)
+	}()
+
+	for {
+		select {
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			tracked := offsets.add(message)
+			paused := false
+			h.service.handleMessage(h.ctx, message.Value, func() {
+				paused = true
+				h.pause()
+			}, func() {
+				offsets.complete(tracked)
+			})
+			if paused {
+				h.resume()
+			}
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+// pause stops fetching from Kafka while a partition waits for room in the send pool
+func (h *consumerGroupHandler) pause() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.waiting++
+	if h.waiting == 1 {
+		logger.Log.Info("Send pool saturated, pausing Kafka fetching")
+		h.group.PauseAll()
+	}
+}
+
+// resume restarts fetching from Kafka once no partition waits for room in the send pool
+func (h *consumerGroupHandler) resume() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.waiting--
+	if h.waiting == 0 {
+		logger.Log.Info("Send pool has room again, resuming Kafka fetching")
+		h.group.ResumeAll()
+	}
+}
+
+// trackedMessage is a message of a partition whose handling may not be finished
+type trackedMessage struct {
+	message *sarama.ConsumerMessage
+	handled bool
+}
+
+// offsetTracker marks the offsets of the messages of a partition in order. Messages are handled
+// concurrently, so a message may be finished before earlier ones; its offset is only marked once
+// every earlier message has been handled too.
+type offsetTracker struct {
+	session sarama.ConsumerGroupSession
+
+	mu      sync.Mutex
+	pending []*trackedMessage // Messages not marked yet, in offset order
+	drained chan struct{}     // Closed once pending is empty, when someone waits for it
+}
+
+// add tracks a message received from the partition
+func (t *offsetTracker) add(message *sarama.ConsumerMessage) *trackedMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tracked := &trackedMessage{message: message}
+	t.pending = append(t.pending, tracked)
+	return tracked
+}
+
+// complete records that a message has been handled and marks the offset of the last message
+// before which everything has been handled
+func (t *offsetTracker) complete(tracked *trackedMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tracked.handled = true
+
+	var last *trackedMessage
+	for len(t.pending) > 0 && t.pending[0].handled {
+		last, t.pending = t.pending[0], t.pending[1:]
+	}
+	if last != nil {
+		t.session.MarkMessage(last.message, "")
+	}
+	if len(t.pending) == 0 && t.drained != nil {
+		close(t.drained)
+		t.drained = nil
+	}
+}
+
+// wait blocks until every tracked message has been handled or ctx is cancelled
+func (t *offsetTracker) wait(ctx context.Context) {
+	t.mu.Lock()
+	if len(t.pending) == 0 {
+		t.mu.Unlock()
+		return
+	}
+	if t.drained == nil {
+		t.drained = make(chan struct{})
+	}
+	drained := t.drained
+	t.mu.Unlock()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,88 @@
+/*
+service/consumer_test.go
+Author: Akhil C
+Description: Tests of the in-order marking of the offsets of messages handled concurrently.
+*/
+
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// markingSession records the offsets marked through a consumer group session
+type markingSession struct {
+	sarama.ConsumerGroupSession
+	marked []int64
+}
+
+func (s *markingSession) MarkMessage(message *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, message.Offset)
+}
+
+func TestOffsetTracker(t *testing.T) {
+	tests := []struct {
+		name       string
+		messages   int
+		completed  []int   // Messages handled, in the order they finish
+		wantMarked []int64 // Offsets marked, in order
+	}{
+		{name: "in order", messages: 3, completed: []int{0, 1, 2}, wantMarked: []int64{0, 1, 2}},
+		{name: "later message first", messages: 2, completed: []int{1, 0}, wantMarked: []int64{1}},
+		{name: "gaps filled later", messages: 4, completed: []int{1, 0, 3, 2}, wantMarked: []int64{1, 3}},
+		{name: "reverse order", messages: 4, completed: []int{3, 2, 1, 0}, wantMarked: []int64{3}},
+		// A message that is never handled, e.g. interrupted by shutdown, holds back the later ones
+		{name: "first message unfinished", messages: 3, completed: []int{1, 2}, wantMarked: nil},
+		{name: "middle message unfinished", messages: 3, completed: []int{0, 2}, wantMarked: []int64{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &markingSession{}
+			tracker := &offsetTracker{session: session}
+			tracked := make([]*trackedMessage, tt.messages)
+			for i := range tracked {
+				tracked[i] = tracker.add(&sarama.ConsumerMessage{Offset: int64(i)})
+			}
+			for _, i := range tt.completed {
+				tracker.complete(tracked[i])
+			}
+			if !slices.Equal(session.marked, tt.wantMarked) {
+				t.Errorf("marked offsets = %v, want %v", session.marked, tt.wantMarked)
+			}
+		})
+	}
+}
+
+func TestOffsetTrackerWait(t *testing.T) {
+	tracker := &offsetTracker{session: &markingSession{}}
+	first := tracker.add(&sarama.ConsumerMessage{Offset: 0})
+	second := tracker.add(&sarama.ConsumerMessage{Offset: 1})
+
+	// Cancelled wait returns with messages still pending
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tracker.wait(ctx)
+
+	waited := make(chan struct{})
+	go func() {
+		tracker.wait(context.Background())
+		close(waited)
+	}()
+	tracker.complete(second)
+	select {
+	case <-waited:
+		t.Fatal("wait() returned before every message was handled")
+	case <-time.After(50 * time.Millisecond):
+	}
+	tracker.complete(first)
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("wait() did not return once every message was handled")
+	}
+}
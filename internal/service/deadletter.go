@@ -0,0 +1,70 @@
+/*
+service/deadletter.go
+Author: Akhil C
+Description: Kafka producer publishing undeliverable notifications to the configured dead-letter topic.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	config "github.com/akhilckenshi/notification/pkg/settings"
+
+	"github.com/IBM/sarama"
+)
+
+// DeadLetterProducer publishes failed notifications to the dead-letter topic
+type DeadLetterProducer struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+// NewDeadLetterProducer creates a producer for the given dead-letter topic
+func NewDeadLetterProducer(brokers []string, topic string) (*DeadLetterProducer, error) {
+	configs := sarama.NewConfig()
+	configs.Producer.Return.Successes = true
+	configs.Producer.RequiredAcks = sarama.WaitForAll
+	configs.Producer.Retry.Max = 5
+
+	producer, err := sarama.NewSyncProducer(brokers, configs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter producer: %v", err)
+	}
+	return &DeadLetterProducer{producer: producer, topic: topic}, nil
+}
+
+// NewDeadLetterProducerFromConfig creates the producer for the configured dead-letter topic.
+// It returns nil without error when no dead-letter topic is configured.
+func NewDeadLetterProducerFromConfig() (*DeadLetterProducer, error) {
+	topic := config.Config.Kafka.DeadLetterTopic
+	if topic == "" {
+		return nil, nil
+	}
+	return NewDeadLetterProducer(kafkaBrokers(), topic)
+}
+
+// Publish sends the dead letter to the topic, keyed by notification ID
+func (p *DeadLetterProducer) Publish(letter models.DeadLetter) error {
+	value, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %v", err)
+	}
+
+	_, _, err = p.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: p.topic,
+		Key:   sarama.StringEncoder(letter.NotificationID),
+		Value: sarama.ByteEncoder(value),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish dead letter to %s: %v", p.topic, err)
+	}
+	return nil
+}
+
+// Close shuts the underlying producer down
+func (p *DeadLetterProducer) Close() error {
+	return p.producer.Close()
+}
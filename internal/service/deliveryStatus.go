@@ -0,0 +1,161 @@
+/*
+service/deliveryStatus.go
+Author: Akhil C
+Description: Applies delivery status updates reported by providers after a notification has been sent.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TwilioStatusUpdate is the content of a Twilio message status callback
+type TwilioStatusUpdate struct {
+	MessageSID   string // MessageSid of the message
+	Status       string // MessageStatus: queued, sending, sent, delivered, read, failed, undelivered, ...
+	ErrorCode    int    // ErrorCode, set for failed and undelivered messages
+	ErrorMessage string // ErrorMessage, when Twilio provides one
+}
+
+// Status callbacks can arrive before the send result holding the message SID is stored. Updates of
+// unknown messages are applied again in the background for a while instead of being lost.
+const (
+	statusRetryInterval = 2 * time.Second
+	statusRetryWindow   = time.Minute
+	maxPendingStatuses  = 1000 // Updates of unknown messages retried at once
+)
+
+// twilioStatusRank orders the Twilio statuses of a message. Callbacks may arrive out of order;
+// an update with a lower rank than the stored status is ignored. Every status Twilio documents
+// is listed; the statuses of inbound messages (receiving, received) never apply to a notification.
+var twilioStatusRank = map[string]int{
+	"receiving":           0,
+	"received":            0,
+	"accepted":            1,
+	"scheduled":           1,
+	"queued":              2,
+	"sending":             3,
+	"sent":                4,
+	"delivered":           5,
+	"partially_delivered": 5,
+	"undelivered":         5,
+	"failed":              5,
+	"canceled":            5,
+	"read":                6,
+}
+
+// twilioStatuses maps Twilio statuses to the status lifecycle of a notification. Statuses
+// that are not listed (e.g. queued, sent) only update the provider status.
+var twilioStatuses = map[string]string{
+	"delivered":           models.StatusDelivered,
+	"partially_delivered": models.StatusDelivered, // Part of the segments reached the handset
+	"read":                models.StatusDelivered,
+	"failed":              models.StatusFailed,
+	"undelivered":         models.StatusFailed,
+	"canceled":            models.StatusFailed, // Scheduled message cancelled before Twilio sent it
+}
+
+// HandleTwilioStatus applies a Twilio status callback to the notification sent as update.MessageSID.
+// Updates of messages not stored yet are applied again for a while in the background;
+// repo.ErrNotificationNotFound is returned when too many updates are waiting already.
+func (s *NotificationService) HandleTwilioStatus(ctx context.Context, update TwilioStatusUpdate) error {
+	if update.MessageSID == "" || update.Status == "" {
+		return &ValidationError{Field: "MessageSid", Message: "MessageSid and MessageStatus are required"}
+	}
+	update.Status = strings.ToLower(update.Status)
+	if _, ok := twilioStatusRank[update.Status]; !ok {
+		logger.Log.Warn(fmt.Sprintf("Ignoring unknown Twilio status %s of message %s", update.Status, update.MessageSID))
+		return nil
+	}
+
+	err := s.applyTwilioStatus(ctx, update)
+	if errors.Is(err, repo.ErrNotificationNotFound) && s.retryTwilioStatus(update) {
+		return nil
+	}
+	return err
+}
+
+// applyTwilioStatus applies a status update to the notification of the message, when it moves the
+// status forward
+func (s *NotificationService) applyTwilioStatus(ctx context.Context, update TwilioStatusUpdate) error {
+	status := update.Status
+	msg, err := s.repo.FindByProviderMessageID(ctx, update.MessageSID)
+	if err != nil {
+		return err
+	}
+	if twilioStatusRank[status] <= twilioStatusRank[msg.ProviderStatus] {
+		return nil // Stale or repeated callback
+	}
+
+	fields := bson.M{"provider_status": status}
+	if update.ErrorCode != 0 {
+		fields["provider_error_code"] = update.ErrorCode
+		fields["provider_error"] = update.ErrorMessage
+	}
+	if status == "read" {
+		fields["read_at"] = time.Now()
+	}
+
+	target, ok := twilioStatuses[status]
+	if !ok || !models.CanTransition(msg.Status, target) {
+		// Only the provider status changes (e.g. read after delivered)
+		return s.repo.UpdateFields(ctx, msg.ID, fields)
+	}
+
+	var reason string
+	if update.ErrorCode != 0 {
+		reason = fmt.Sprintf("twilio error %d: %s", update.ErrorCode, update.ErrorMessage)
+	} else if status == "canceled" {
+		reason = "message canceled by twilio"
+	}
+	err = s.transition(ctx, msg, target, reason, fields)
+	if errors.Is(err, repo.ErrStaleStatus) {
+		// The notification changed since it was read; keep the provider details anyway
+		logger.Log.Warn(fmt.Sprintf("Status %s of message %s no longer applies to notification %s", status, update.MessageSID, msg.ID.Hex()))
+		return s.repo.UpdateFields(ctx, msg.ID, fields)
+	}
+	return err
+}
+
+// retryTwilioStatus applies the update of a message that is not stored yet every
+// statusRetryInterval, until it applies or statusRetryWindow has passed. It returns false
+// when the update cannot be retried.
+func (s *NotificationService) retryTwilioStatus(update TwilioStatusUpdate) bool {
+	if s.pendingStatuses.Add(1) > maxPendingStatuses {
+		s.pendingStatuses.Add(-1)
+		return false
+	}
+	ctx := s.ctx
+	started := s.goBackground(func() {
+		defer s.pendingStatuses.Add(-1)
+		for deadline := time.Now().Add(statusRetryWindow); time.Now().Before(deadline); {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(statusRetryInterval):
+			}
+			err := s.applyTwilioStatus(ctx, update)
+			if !errors.Is(err, repo.ErrNotificationNotFound) {
+				if err != nil {
+					logger.Log.Error(fmt.Sprintf("Failed to apply status %s of message %s: %v", update.Status, update.MessageSID, err))
+				}
+				return
+			}
+		}
+		logger.Log.Warn(fmt.Sprintf("Dropping status %s of unknown message %s", update.Status, update.MessageSID))
+	})
+	if !started {
+		s.pendingStatuses.Add(-1)
+	}
+	return started
+}
@@ -0,0 +1,110 @@
+/*
+service/idempotency.go
+Author: Akhil C
+Description: Idempotent storage of notifications so that Kafka redeliveries and client retries do not send a notification twice.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/notifications"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	config "github.com/akhilckenshi/notification/pkg/settings"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const defaultDedupWindow = 24 * time.Hour // Dedup window used when idempotency.windowMinutes is not set
+
+// storeRetryPolicy paces the attempts to store a consumed notification while the database fails,
+// about 30 seconds in total so a partition is not held much longer than a rebalance allows
+var storeRetryPolicy = notifications.RetryPolicy{MaxAttempts: 6, BaseDelay: time.Second, MaxDelay: 15 * time.Second, Jitter: 0.2}
+
+// dedupWindow returns how long an idempotency key suppresses duplicates
+func dedupWindow() time.Duration {
+	if config.Config.Idempotency.WindowMinutes > 0 {
+		return time.Duration(config.Config.Idempotency.WindowMinutes) * time.Minute
+	}
+	return defaultDedupWindow
+}
+
+// storeOnce stores the notification unless another notification with the same idempotency
+// key was stored within the dedup window. In that case the original notification is
+// returned together with duplicate set to true and nothing is stored.
+func (s *NotificationService) storeOnce(ctx context.Context, msg *models.Notification) (stored *models.Notification, duplicate bool, err error) {
+	if msg.IdempotencyKey == "" {
+		return msg, false, s.repo.StoreNotificationInformation(ctx, msg)
+	}
+
+	// A second pass is only needed when another instance stored the key concurrently
+	for attempt := 0; attempt < 2; attempt++ {
+		existing, err := s.lookupDuplicates(ctx, []*models.Notification{msg})
+		if err != nil {
+			return nil, false, err
+		}
+		if original, ok := existing[msg.IdempotencyKey]; ok {
+			return original, true, nil
+		}
+
+		err = s.repo.StoreNotificationInformation(ctx, msg)
+		if !errors.Is(err, repo.ErrDuplicateNotification) {
+			return msg, false, err
+		}
+	}
+	return nil, false, repo.ErrDuplicateNotification
+}
+
+// storeRetrying stores the notification like storeOnce, retrying failed attempts with backoff so a
+// transient database error does not drop a consumed message. It gives up once the attempts of
+// storeRetryPolicy are exhausted or ctx is cancelled.
+func (s *NotificationService) storeRetrying(ctx context.Context, msg *models.Notification) (stored *models.Notification, duplicate bool, err error) {
+	for attempt := 1; ; attempt++ {
+		stored, duplicate, err = s.storeOnce(ctx, msg)
+		if err == nil || attempt >= storeRetryPolicy.MaxAttempts {
+			return stored, duplicate, err
+		}
+		delay := storeRetryPolicy.Backoff(attempt)
+		logger.Log.Warn(fmt.Sprintf("Failed to store %s notification (idempotency key %s, attempt %d), retrying in %s: %v", msg.Type, msg.IdempotencyKey, attempt, delay.Round(time.Millisecond), err))
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// lookupDuplicates returns the stored notifications, keyed by idempotency key, that are still
+// within their dedup window for the keys of msgs. Keys whose window has ended are released
+// so the new notifications can be stored.
+func (s *NotificationService) lookupDuplicates(ctx context.Context, msgs []*models.Notification) (map[string]*models.Notification, error) {
+	var keys []string
+	for _, msg := range msgs {
+		if msg.IdempotencyKey != "" {
+			keys = append(keys, msg.IdempotencyKey)
+		}
+	}
+
+	found, err := s.repo.FindByIdempotencyKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var expired []primitive.ObjectID
+	for key, original := range found {
+		if !original.DedupExpiresAt.IsZero() && now.After(original.DedupExpiresAt) {
+			expired = append(expired, original.ID)
+			delete(found, key)
+		}
+	}
+	if err := s.repo.ReleaseIdempotencyKeys(ctx, expired); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
@@ -0,0 +1,42 @@
+/*
+service/lifecycle.go
+Author: Akhil C
+Description: Runs the background work of the notification service on its lifecycle context and waits for it on shutdown.
+*/
+
+package service
+
+import "context"
+
+// Start runs the Kafka consumer, the scheduler and the recovery of pending notifications until ctx
+// is cancelled. Notifications submitted through the API are dispatched on ctx as well, so they
+// stop with the service instead of outliving it. Wait blocks until this background work has
+// stopped.
+func (s *NotificationService) Start(ctx context.Context) {
+	s.ctx = ctx
+	s.goBackground(func() { s.runRecovery(ctx) })
+	s.goBackground(func() { s.MessageConsumer(ctx) })
+	s.goBackground(func() { s.RunScheduler(ctx) })
+}
+
+// Wait blocks until the background work of the service and the workers of its dispatcher have
+// stopped, which happens once the context passed to Start is cancelled
+func (s *NotificationService) Wait() {
+	s.background.Wait()
+	s.dispatcher.Wait()
+}
+
+// goBackground runs fn in a goroutine Wait waits for. Nothing is started once the service stopped,
+// in which case false is returned; the notifications fn would have dispatched stay queued in the
+// repository.
+func (s *NotificationService) goBackground(fn func()) bool {
+	if s.ctx.Err() != nil {
+		return false
+	}
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		fn()
+	}()
+	return true
+}
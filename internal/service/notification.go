@@ -9,89 +9,392 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/akhilckenshi/notification/internal/dispatcher"
 	"github.com/akhilckenshi/notification/internal/models"
 	"github.com/akhilckenshi/notification/internal/notifications"
+	"github.com/akhilckenshi/notification/internal/ratelimit"
 	"github.com/akhilckenshi/notification/internal/repo"
-	config "github.com/akhilckenshi/notification/pkg/settings"
+	"github.com/akhilckenshi/notification/internal/templates"
+	"github.com/akhilckenshi/notification/pkg/logger"
 	"github.com/akhilckenshi/notification/pkg/utils"
-
-	"github.com/IBM/sarama"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// notificationLease is how long a worker holds a notification it dispatches; a notification whose
+// worker stopped is taken over once the lease expires
+const notificationLease = 5 * time.Minute
+
 // NotificationService handles business logic for notification
 type NotificationService struct {
-	repo *repo.Notification
+	repo       *repo.Notification
+	registry   *notifications.Registry
+	templates  *TemplateService
+	deadLetter *DeadLetterProducer // Optional, nil when no dead-letter topic is configured
+	dispatcher *dispatcher.Dispatcher
+	pool       *sendPool          // Bounds the notifications queued or being sent, in total and per channel
+	limiter    *ratelimit.Limiter // Rate limits of channels and organizations; optional
+
+	// Recipients suppressed after bounces and complaints, recipient preferences, the
+	// schedules of notifications held until their send time and quiet hours; optional
+	suppressions *repo.Suppression
+	preferences  *repo.Preference
+	schedules    *repo.Schedule
+	quietHours   *repo.QuietHours
+
+	// Lifecycle of the background work, set by Start, and the goroutines Wait waits for
+	ctx        context.Context
+	background sync.WaitGroup
+
+	pendingStatuses atomic.Int32 // Provider status updates of messages not stored yet, being retried
 }
 
 // NewNotificationService creates a new instance of NotificationService
-func NewNotificationService(repo *repo.Notification) *NotificationService {
-	return &NotificationService{repo: repo}
+func NewNotificationService(repo *repo.Notification, registry *notifications.Registry, templates *TemplateService, deadLetter *DeadLetterProducer, dispatcher *dispatcher.Dispatcher, limiter *ratelimit.Limiter, suppressions *repo.Suppression, preferences *repo.Preference, schedules *repo.Schedule, quietHours *repo.QuietHours) *NotificationService {
+	return &NotificationService{repo: repo, registry: registry, templates: templates, deadLetter: deadLetter, dispatcher: dispatcher, pool: newSendPool(), limiter: limiter, suppressions: suppressions, preferences: preferences, schedules: schedules, quietHours: quietHours, ctx: context.Background()}
 }
 
-// Consumer
-func (s *NotificationService) MessageConsumer() {
-	configs := sarama.NewConfig()
-	consumer, err := sarama.NewConsumer([]string{config.Config.KafkaPort}, configs)
+// handleMessage decodes a notification message received from Kafka, stores it as queued and queues
+// it on the dispatcher queue of its priority. It returns once the notification is queued; done is
+// called once the message has been handled. waiting is called before waiting for room in the
+// send pool. When ctx is cancelled before the notification is dispatched, done is not called.
+// A notification that cannot be stored is retried, then dead-lettered.
+func (s *NotificationService) handleMessage(ctx context.Context, data []byte, waiting, done func()) {
+	msg, err := s.UnmarshelChatMessage(data)
 	if err != nil {
-		fmt.Println("Error creating Kafka consumer:", err)
+		logger.Log.Error(fmt.Sprintf("Failed to decode notification message: %v", err))
+		done()
 		return
 	}
-	defer consumer.Close()
-	partitionConsumer, err := consumer.ConsumePartition(config.Config.KafkaTopic, 0, sarama.OffsetNewest)
+
+	logger.Log.Debug(fmt.Sprintf("Received %s notification (idempotency key %s)", msg.Type, msg.IdempotencyKey))
+	original, duplicate, err := s.storeRetrying(ctx, msg)
 	if err != nil {
-		fmt.Println("Error creating partition consumer:", err)
+		// Interrupted by shutdown: the message is consumed again once the consumer restarts
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Log.Error(fmt.Sprintf("Failed to store %s notification (idempotency key %s): %v", msg.Type, msg.IdempotencyKey, err))
+		// The message is done once it is kept on the dead-letter topic; otherwise its offset stays
+		// unmarked and it is consumed again after a restart or rebalance
+		if s.publishDeadLetter(msg, err) {
+			done()
+		}
 		return
 	}
-	defer partitionConsumer.Close()
-	fmt.Println("Kafka consumer started")
-	for {
-		select {
-		case message := <-partitionConsumer.Messages():
-			msg, err := s.UnmarshelChatMessage(message.Value)
-			if err != nil {
-				fmt.Println("Error unmarshalling message:", err)
-				continue
+	if duplicate {
+		// Redelivered message: there is nothing to do once the original was processed, or while a worker sends it
+		if !models.IsPending(original.Status) || original.Leased(time.Now()) {
+			logger.Log.Info(fmt.Sprintf("Skipping duplicate notification with idempotency key %s (original %s, status %s)", msg.IdempotencyKey, original.ID.Hex(), original.Status))
+			done()
+			return
+		}
+		// The original was never sent (e.g. the consumer stopped before dispatching it): it is sent now
+		logger.Log.Info(fmt.Sprintf("Resuming %s notification %s redelivered with idempotency key %s", original.Status, original.ID.Hex(), msg.IdempotencyKey))
+		original.Payload = msg.Payload
+		msg = original
+	}
+
+	// Send notification through the channel registered for its type, from the queue of its priority
+	if err := s.enqueue(ctx, msg, waiting, done); err != nil && ctx.Err() == nil {
+		// Not queued (e.g. the dispatcher stopped): the notification stays pending in the repository
+		// and is sent by the recovery sweep, so the message is done
+		done()
+	}
+}
+
+// enqueue queues the notification on the dispatcher queue of its priority once the send pool has
+// room for it. waiting, when not nil, is called before waiting for room; done, when not nil, is
+// called once the notification has been dispatched, unless ctx was cancelled meanwhile.
+func (s *NotificationService) enqueue(ctx context.Context, msg *models.Notification, waiting, done func()) error {
+	release, err := s.pool.acquire(ctx, msg.Type, waiting)
+	if err == nil {
+		err = s.dispatcher.Submit(ctx, msg.Priority, func() {
+			defer release()
+			s.dispatchLeased(ctx, msg)
+			// A dispatch interrupted by shutdown is not done: the message is consumed again and the
+			// notification, still pending, is resumed
+			if done != nil && ctx.Err() == nil {
+				done()
 			}
+		})
+		if err != nil {
+			release()
+		}
+	}
+	if err != nil {
+		// The notification stays queued in the repository
+		logger.Log.Error(fmt.Sprintf("Failed to queue notification %s: %v", msg.ID.Hex(), err))
+	}
+	return err
+}
 
-			// Determine message content based on priority
-			// var messageContent string
-			// switch msg.Priority {
-			// case "high":
-			// 	messageContent = "Do it fast - High priority"
-			// case "medium":
-			// 	messageContent = "Do it soon - Medium priority"
-			// case "low":
-			// 	messageContent = "Do it when possible - Low priority"
-			// default:
-			// 	messageContent = "Do it as per priority"
-			// }
-			// Send notification based on message type and priority
-			switch msg.Type {
-			case "email":
-				fmt.Println("Processing Email notification")
-				msg.From = config.Config.Email.Id
-				notifications.SendEmail(msg.To, msg.Subject, msg.Message)
-			case "whatsapp":
-				fmt.Println("Processing WhatsApp notification")
-				msg.From = config.Config.WhatsAppFromNumber
-				notifications.SendWhatsAppMessage(msg.To, msg.Subject, msg.Message)
-			default:
-				fmt.Println("Unknown message type:", msg.Type)
+// dispatchLeased dispatches the notification while holding its lease, so a notification queued twice
+// (e.g. redelivered by Kafka while waiting in a queue) is sent by a single worker. The stored
+// notification is dispatched; nothing is done when another worker holds the lease or the
+// notification is not waiting to be sent anymore.
+func (s *NotificationService) dispatchLeased(ctx context.Context, msg *models.Notification) {
+	leaseID := primitive.NewObjectID().Hex()
+	stored, err := s.repo.Lease(ctx, msg.ID, leaseID, time.Now().Add(notificationLease))
+	if errors.Is(err, repo.ErrNotificationLeased) {
+		logger.Log.Debug(fmt.Sprintf("Not dispatching notification %s: %v", msg.ID.Hex(), err))
+		return
+	}
+	if err != nil {
+		return // The notification stays pending in the repository
+	}
+	defer func() {
+		// The lease is released even when ctx was cancelled during the dispatch
+		if err := s.repo.ReleaseLease(context.WithoutCancel(ctx), stored.ID, leaseID); err != nil {
+			logger.Log.Error(err.Error())
+		}
+	}()
+	stored.Payload = msg.Payload
+
+	// The previous worker stopped while sending: its attempt is retried
+	if stored.Status == models.StatusSending {
+		if err := s.transition(ctx, stored, models.StatusRetrying, "attempt interrupted", nil); err != nil {
+			return
+		}
+	}
+	s.dispatch(ctx, stored)
+}
+
+// dispatch sends the notification through the Notifier registered for its type and
+// records every status change. Notifications with a future send time or inside quiet hours are
+// scheduled instead. Failed attempts are retried according to the retry policy of the channel:
+// the next attempt is scheduled after the backoff delay. Notifications that cannot be delivered
+// are published to the dead-letter topic.
+func (s *NotificationService) dispatch(ctx context.Context, msg *models.Notification) {
+	notifier, err := s.registry.Get(msg.Type)
+	if err != nil {
+		// Only *notifications.UnknownChannelError is returned by the registry
+		logger.Log.Warn(fmt.Sprintf("Rejecting notification %s: %v", msg.ID.Hex(), err))
+		s.transition(ctx, msg, models.StatusFailed, err.Error(), nil)
+		s.publishDeadLetter(msg, err)
+		return
+	}
+
+	// Notifications are held until their send time, then dispatched again by the scheduler
+	if s.holdUntilSendAt(ctx, msg) {
+		return
+	}
+
+	// Suppressed recipients are skipped; they are not a delivery failure
+	if suppression := s.activeSuppression(ctx, msg); suppression != nil {
+		reason := fmt.Sprintf("recipient %s is suppressed (%s)", suppression.Recipient, suppression.Reason)
+		logger.Log.Info(fmt.Sprintf("Skipping notification %s: %s", msg.ID.Hex(), reason))
+		s.transition(ctx, msg, models.StatusSuppressed, reason, nil)
+		return
+	}
+	if reason, windowStart := s.preferenceBlock(ctx, msg); reason != "" {
+		// Outside the hours of its category the notification is deferred to the next window, not dropped
+		if windowStart != nil && s.schedules != nil {
+			s.schedule(ctx, msg, *windowStart, reason)
+			return
+		}
+		logger.Log.Info(fmt.Sprintf("Skipping notification %s: %s", msg.ID.Hex(), reason))
+		s.transition(ctx, msg, models.StatusSkipped, reason, nil)
+		return
+	}
+	// Non-urgent notifications inside quiet hours are deferred to the end of the quiet hours
+	if end := s.quietHoursEnd(ctx, msg); end != nil {
+		if held, _ := s.schedule(ctx, msg, *end, fmt.Sprintf("quiet hours until %s", end.Format(time.RFC3339))); held {
+			return
+		}
+	}
+	// Notifications over the rate limit of their channel or organization are deferred, not dropped
+	if s.deferRateLimited(ctx, msg) {
+		return
+	}
+
+	// Templates are rendered at send time; the rendered content is stored with the first attempt
+	// and sent again by the next ones
+	var rendered bson.M
+	if msg.TemplateID != "" && msg.Attempts == 0 {
+		if err := s.renderTemplate(ctx, msg); err != nil {
+			logger.Log.Error(fmt.Sprintf("Failed to render template %s for notification %s: %v", msg.TemplateID, msg.ID.Hex(), err))
+			s.transition(ctx, msg, models.StatusFailed, err.Error(), nil)
+			s.publishDeadLetter(msg, err)
+			return
+		}
+		rendered = bson.M{
+			"subject":          msg.Subject,
+			"message":          msg.Message,
+			"plain_text":       msg.PlainText,
+			"template_version": msg.TemplateVersion,
+		}
+	}
+
+	policy := notifications.RetryPolicyFor(msg.Type)
+	for {
+		if err := s.transition(ctx, msg, models.StatusSending, "", rendered); err != nil {
+			return
+		}
+		rendered = nil
+
+		msg.Attempts++
+		logger.Log.Debug(fmt.Sprintf("Sending %s notification %s (attempt %d)", msg.Type, msg.ID.Hex(), msg.Attempts))
+		result, err := notifier.Send(ctx, msg)
+		fields := providerFields(msg, result)
+		fields["attempts"] = msg.Attempts
+		if err == nil {
+			s.transition(ctx, msg, models.StatusSent, "", fields)
+			return
+		}
+
+		// Providers over their rate limit did not try: the attempt does not count and is made again once the limit allows it
+		if limited := rateLimited(err); limited != nil {
+			msg.Attempts--
+			fields["attempts"] = msg.Attempts
+			if err := s.transition(ctx, msg, models.StatusRetrying, limited.Error(), fields); err != nil {
+				return
+			}
+			// Like the limits of channels and organizations, long waits are scheduled instead of taking the worker
+			if limited.RetryAfter > maxRateLimitWait && s.schedules != nil {
+				logger.Log.Info(fmt.Sprintf("Deferring notification %s: %v", msg.ID.Hex(), limited))
+				s.schedule(ctx, msg, time.Now().Add(limited.RetryAfter), limited.Error())
+				return
 			}
-			fmt.Println("Received message:", msg)
-			err = s.repo.StoreNotificationInformation(*msg)
-			if err != nil {
-				fmt.Println("Error storing message in repository:", err)
-				continue
+			if !waitRateLimit(ctx, limited) {
+				logger.Log.Warn(fmt.Sprintf("Stopped waiting for the rate limit of notification %s: %v", msg.ID.Hex(), ctx.Err()))
+				return
 			}
-		case err := <-partitionConsumer.Errors():
-			fmt.Println("Kafka consumer error:", err)
+			continue
 		}
+
+		logger.Log.Error(fmt.Sprintf("Failed to send %s notification to %s (attempt %d): %v", msg.Type, msg.To, msg.Attempts, err))
+		if !notifications.IsRetryable(err) || msg.Attempts >= policy.MaxAttempts {
+			s.transition(ctx, msg, models.StatusFailed, err.Error(), fields)
+			s.publishDeadLetter(msg, err)
+			return
+		}
+
+		delay := policy.Backoff(msg.Attempts)
+		reason := fmt.Sprintf("attempt %d failed, retrying in %s: %v", msg.Attempts, delay.Round(time.Millisecond), err)
+		if err := s.transition(ctx, msg, models.StatusRetrying, reason, fields); err != nil {
+			return
+		}
+
+		// The next attempt is scheduled, so the worker is free meanwhile; without schedule store
+		// the worker waits for it
+		if s.schedules != nil {
+			s.schedule(ctx, msg, time.Now().Add(delay), reason)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			logger.Log.Warn(fmt.Sprintf("Stopped retrying notification %s: %v", msg.ID.Hex(), ctx.Err()))
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// activeSuppression returns the suppression of the recipient of the notification, or nil when
+// it may be sent. Notifications are sent when the suppression list cannot be read.
+func (s *NotificationService) activeSuppression(ctx context.Context, msg *models.Notification) *models.Suppression {
+	if s.suppressions == nil {
+		return nil
+	}
+	suppression, err := s.suppressions.Active(ctx, msg.OrganizationID, msg.Type, msg.To)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Failed to check suppression of notification %s: %v", msg.ID.Hex(), err))
+		return nil
+	}
+	return suppression
+}
+
+// providerFields copies the details reported by the channel for an attempt to the notification
+// and returns the fields to store with the next status transition
+func providerFields(msg *models.Notification, result notifications.DeliveryResult) bson.M {
+	fields := bson.M{}
+	if result.From != "" {
+		msg.From = result.From
+		fields["from"] = msg.From
+	}
+	if result.Provider != "" {
+		msg.Provider = result.Provider
+		fields["provider"] = msg.Provider
+	}
+	if result.Segments > 0 {
+		msg.Segments = result.Segments
+		fields["segments"] = msg.Segments
+	}
+
+	// Provider details always describe the last attempt
+	msg.ProviderMessageID = result.ProviderMessageID
+	msg.ProviderStatus = result.ProviderStatus
+	msg.ProviderErrorCode = result.ErrorCode
+	msg.ProviderError = result.ErrorMessage
+	fields["provider_message_id"] = msg.ProviderMessageID
+	fields["provider_status"] = msg.ProviderStatus
+	fields["provider_error_code"] = msg.ProviderErrorCode
+	fields["provider_error"] = msg.ProviderError
+	return fields
+}
+
+// renderTemplate renders the template referenced by the notification into its subject and message
+func (s *NotificationService) renderTemplate(ctx context.Context, msg *models.Notification) error {
+	template, rendered, err := s.templates.RenderTemplate(ctx, msg.OrganizationID, msg.TemplateID, msg.TemplateVersion, msg.Type, msg.Locale, msg.Data)
+	if err != nil {
+		return err
+	}
+	msg.Subject = rendered.Subject
+	msg.Message = rendered.Body()
+	if rendered.HTML != "" {
+		msg.PlainText = rendered.Text // Sent as the text/plain alternative of the HTML body
+	}
+	msg.TemplateVersion = template.Version
+	return nil
+}
+
+// publishDeadLetter publishes the original payload of a failed notification to the dead-letter topic
+// and reports whether it was published. The payload is not persisted, so for notifications read
+// back from the repository (e.g. released by the scheduler) it is rebuilt from the stored notification.
+func (s *NotificationService) publishDeadLetter(msg *models.Notification, cause error) bool {
+	if s.deadLetter == nil {
+		return false
+	}
+	payload := msg.Payload
+	if payload == nil {
+		payload = notifierOf(msg)
+	}
+	letter := models.DeadLetter{
+		Payload:        *payload,
+		NotificationID: msg.ID.Hex(),
+		Type:           msg.Type,
+		Attempts:       msg.Attempts,
+		Error:          cause.Error(),
+		Retryable:      notifications.IsRetryable(cause),
+		FailedAt:       time.Now(),
+	}
+	if err := s.deadLetter.Publish(letter); err != nil {
+		logger.Log.Error(fmt.Sprintf("Failed to dead-letter notification %s: %v", msg.ID.Hex(), err))
+		return false
 	}
+	return true
+}
+
+// transition moves the notification to the given status and persists the change together
+// with any additional fields. Errors are logged and returned so callers can stop processing.
+func (s *NotificationService) transition(ctx context.Context, msg *models.Notification, status, reason string, fields bson.M) error {
+	change, err := msg.Transition(status, reason)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Notification %s: %v", msg.ID.Hex(), err))
+		return err
+	}
+	if err := s.repo.RecordTransition(ctx, msg.ID, change, fields); err != nil {
+		logger.Log.Error(fmt.Sprintf("Failed to record status %s for notification %s: %v", status, msg.ID.Hex(), err))
+		return err
+	}
+	return nil
 }
 
 // Unmarshal byte to Notification structure from Notifier
@@ -99,26 +402,124 @@ func (n *NotificationService) UnmarshelChatMessage(data []byte) (*models.Notific
 	var notifier models.Notifier // Create an instance of Notifier for unmarshalling
 	err := json.Unmarshal(data, &notifier)
 	if err != nil {
-		fmt.Println("Error unmarshalling Notifier:", err)
 		return nil, err
 	}
 
-	// Map Notifier fields to Notification, assigning ID to NotificationID
+	return newNotification(&notifier), nil
+}
+
+// newNotification maps a Notifier payload to a queued Notification, assigning ID to NotificationID
+func newNotification(notifier *models.Notifier) *models.Notification {
+	if notifier.CreatedAt.IsZero() {
+		notifier.CreatedAt = time.Now()
+	}
+
+	// The idempotency key defaults to the producer assigned ID
+	idempotencyKey := notifier.IdempotencyKey
+	if idempotencyKey == "" && !notifier.ID.IsZero() {
+		idempotencyKey = notifier.ID.Hex()
+	}
+
 	notification := &models.Notification{
-		NotificationID: notifier.ID,
-		OrganizationID: notifier.OrganizationID,
-		To:             notifier.To,
-		From:           notifier.From,
-		Type:           notifier.Type,
-		Priority:       notifier.Priority,
-		Subject:        notifier.Subject,
-		Message:        notifier.Message,
-		Status:         "Delivered",
-		CreatedAt:      notifier.CreatedAt,
-		UpdatedAt:      time.Now(),
-	}
-
-	return notification, nil
+		NotificationID:  notifier.ID,
+		OrganizationID:  notifier.OrganizationID,
+		To:              notifier.To,
+		From:            notifier.From,
+		Type:            notifier.Type,
+		Priority:        notifier.Priority,
+		Category:        notifier.Category,
+		Subject:         notifier.Subject,
+		Message:         notifier.Message,
+		PlainText:       notifier.PlainText,
+		Cc:              notifier.Cc,
+		Bcc:             notifier.Bcc,
+		ReplyTo:         notifier.ReplyTo,
+		Attachments:     notifier.Attachments,
+		TemplateID:      notifier.TemplateID,
+		TemplateVersion: notifier.TemplateVersion,
+		Data:            notifier.Data,
+		Locale:          templates.NormalizeLocale(notifier.Locale),
+		CreatedAt:       notifier.CreatedAt,
+		Payload:         notifier,
+
+		ContentSID:       notifier.ContentSID,
+		ContentVariables: notifier.ContentVariables,
+		MediaURLs:        notifier.MediaURLs,
+
+		TimeZone: notifier.TimeZone,
+	}
+	// Send times without offset are resolved in the time zone of the recipient when dispatched
+	if sendAt, err := time.Parse(time.RFC3339, notifier.SendAt); err == nil {
+		notification.SendAt = &sendAt
+	} else {
+		notification.LocalSendAt = notifier.SendAt
+	}
+	if idempotencyKey != "" {
+		notification.IdempotencyKey = idempotencyKey
+		notification.DedupExpiresAt = time.Now().Add(dedupWindow())
+	}
+	// Every notification starts its lifecycle as queued
+	notification.Transition(models.StatusQueued, "")
+
+	return notification
+}
+
+// notifierOf rebuilds the Notifier payload a stored notification was created from
+func notifierOf(msg *models.Notification) *models.Notifier {
+	notifier := &models.Notifier{
+		ID:              msg.NotificationID,
+		OrganizationID:  msg.OrganizationID,
+		To:              msg.To,
+		From:            msg.From,
+		Type:            msg.Type,
+		Priority:        msg.Priority,
+		Category:        msg.Category,
+		Subject:         msg.Subject,
+		Message:         msg.Message,
+		PlainText:       msg.PlainText,
+		Cc:              msg.Cc,
+		Bcc:             msg.Bcc,
+		ReplyTo:         msg.ReplyTo,
+		Attachments:     msg.Attachments,
+		IdempotencyKey:  msg.IdempotencyKey,
+		TemplateID:      msg.TemplateID,
+		TemplateVersion: msg.TemplateVersion,
+		Data:            msg.Data,
+		Locale:          msg.Locale,
+		SendAt:          msg.LocalSendAt,
+		TimeZone:        msg.TimeZone,
+		CreatedAt:       msg.CreatedAt,
+
+		ContentSID:       msg.ContentSID,
+		ContentVariables: msg.ContentVariables,
+		MediaURLs:        msg.MediaURLs,
+	}
+	if msg.SendAt != nil {
+		notifier.SendAt = msg.SendAt.Format(time.RFC3339)
+	}
+	return notifier
+}
+
+// SubmitNotification validates a notification submitted through the API, stores it as queued
+// and dispatches it in the background through the same priority queues as the Kafka consumer.
+// If the idempotency key was already used within the dedup window, the original notification
+// is returned with duplicate set to true and nothing is sent.
+// A *ValidationError is returned when the payload is invalid.
+func (s *NotificationService) SubmitNotification(ctx context.Context, notifier *models.Notifier) (notification *models.Notification, duplicate bool, err error) {
+	if err := s.validateNotifier(ctx, notifier); err != nil {
+		return nil, false, err
+	}
+
+	msg := newNotification(notifier)
+	stored, duplicate, err := s.storeOnce(ctx, msg)
+	if err != nil || duplicate {
+		return stored, duplicate, err
+	}
+
+	// The request context ends with the HTTP call, so delivery runs on the context of the service
+	s.goBackground(func() { s.enqueue(s.ctx, msg, nil, nil) })
+
+	return msg, false, nil
 }
 
 // GetNotification retrieves a list of all notifications from the repository and returns them.
@@ -0,0 +1,77 @@
+/*
+service/pool.go
+Author: Akhil C
+Description: Bounds the notifications being sent at once, in total and per channel, so a slow channel cannot take every worker.
+*/
+
+package service
+
+import (
+	"context"
+
+	config "github.com/akhilckenshi/notification/pkg/settings"
+)
+
+const defaultMaxInFlight = 500 // Notifications in flight when dispatcher.maxInFlight is not set
+
+// sendPool admits notifications to the dispatcher queues. A notification holds a slot of the pool,
+// and of its channel when the channel is limited, from the moment it is queued until it has
+// been dispatched.
+type sendPool struct {
+	total    chan struct{}
+	channels map[string]chan struct{}
+}
+
+// newSendPool creates the pool configured under dispatcher.maxInFlight and dispatcher.channelConcurrency
+func newSendPool() *sendPool {
+	conf := config.Config.Dispatcher
+	maxInFlight := conf.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = defaultMaxInFlight
+	}
+	channels := map[string]chan struct{}{}
+	for channel, limit := range conf.ChannelConcurrency {
+		if limit > 0 && limit < maxInFlight {
+			channels[channel] = make(chan struct{}, limit)
+		}
+	}
+	return &sendPool{total: make(chan struct{}, maxInFlight), channels: channels}
+}
+
+// acquire takes a slot for a notification of channel, waiting until one is free or ctx is
+// cancelled. waiting, when not nil, is called once before waiting. The returned function
+// releases the slot.
+func (p *sendPool) acquire(ctx context.Context, channel string, waiting func()) (func(), error) {
+	// The channel slot is taken first, so a saturated channel does not hold slots of the pool
+	slots := []chan struct{}{p.total}
+	if channelSlots, ok := p.channels[channel]; ok {
+		slots = []chan struct{}{channelSlots, p.total}
+	}
+
+	var taken []chan struct{}
+	release := func() {
+		for _, slot := range taken {
+			<-slot
+		}
+	}
+	for _, slot := range slots {
+		select {
+		case slot <- struct{}{}:
+			taken = append(taken, slot)
+			continue
+		default:
+		}
+		if waiting != nil {
+			waiting()
+			waiting = nil
+		}
+		select {
+		case slot <- struct{}{}:
+			taken = append(taken, slot)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
@@ -0,0 +1,219 @@
+/*
+service/preference.go
+Author: Akhil C
+Description: Service to manage the notification preferences of recipients and apply them when notifications are dispatched.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	_ "time/tzdata" // Time zones of recipients; the runtime image has no zoneinfo
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/notifications"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	config "github.com/akhilckenshi/notification/pkg/settings"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Categories accepted on notifications and preferences
+var validCategories = map[string]bool{
+	models.CategoryTransactional: true,
+	models.CategorySecurity:      true,
+	models.CategoryBilling:       true,
+	models.CategoryMarketing:     true,
+}
+
+// defaultBypassCategories are sent regardless of preferences when preferences.bypassCategories is not configured
+var defaultBypassCategories = []string{models.CategorySecurity, models.CategoryTransactional}
+
+// PreferenceService handles business logic for recipient preferences
+type PreferenceService struct {
+	repo *repo.Preference
+}
+
+// NewPreferenceService creates a new instance of PreferenceService
+func NewPreferenceService(repo *repo.Preference) *PreferenceService {
+	return &PreferenceService{repo: repo}
+}
+
+// validatePreference checks the categories, channels, hours and time zone of preferences
+func validatePreference(preference *models.Preference) error {
+	if preference.OrganizationID.IsZero() {
+		return &ValidationError{Field: "organization_id", Message: "organization ID is required"}
+	}
+	if strings.TrimSpace(preference.Recipient) == "" {
+		return &ValidationError{Field: "recipient", Message: "recipient is required"}
+	}
+	if _, err := time.LoadLocation(preference.TimeZone); err != nil {
+		return &ValidationError{Field: "time_zone", Message: "must be an IANA time zone (e.g. Europe/Paris)"}
+	}
+	channels := []string{notifications.TypeEmail, notifications.TypeSMS, notifications.TypeWhatsApp}
+	for category, categoryPreference := range preference.Categories {
+		if !validCategories[category] {
+			return &ValidationError{Field: "categories", Message: fmt.Sprintf("unknown category %q", category)}
+		}
+		for _, channel := range categoryPreference.Channels {
+			if !slices.Contains(channels, channel) {
+				return &ValidationError{Field: "categories", Message: fmt.Sprintf("unknown channel %q for category %s", channel, category)}
+			}
+		}
+		if hours := categoryPreference.Hours; hours != nil {
+			start, startErr := parseClock(hours.Start)
+			end, endErr := parseClock(hours.End)
+			if startErr != nil || endErr != nil {
+				return &ValidationError{Field: "categories", Message: fmt.Sprintf("hours of category %s must be given as HH:MM", category)}
+			}
+			if start == end {
+				return &ValidationError{Field: "categories", Message: fmt.Sprintf("hours of category %s must not start and end at the same time", category)}
+			}
+		}
+	}
+	return nil
+}
+
+// SavePreference stores the preferences of a recipient, replacing the previous ones
+func (s *PreferenceService) SavePreference(ctx context.Context, preference *models.Preference) error {
+	if err := validatePreference(preference); err != nil {
+		return err
+	}
+	if preference.Categories == nil {
+		preference.Categories = map[string]models.CategoryPreference{}
+	}
+	return s.repo.Save(ctx, preference)
+}
+
+// GetPreference returns the preferences of a recipient of an organization
+func (s *PreferenceService) GetPreference(ctx context.Context, orgID primitive.ObjectID, recipient string) (*models.Preference, error) {
+	return s.repo.Find(ctx, orgID, recipient)
+}
+
+// ListPreferences returns the preferences of every recipient of an organization
+func (s *PreferenceService) ListPreferences(ctx context.Context, orgID primitive.ObjectID) ([]*models.Preference, error) {
+	return s.repo.List(ctx, orgID)
+}
+
+// DeletePreference removes the preferences of a recipient
+func (s *PreferenceService) DeletePreference(ctx context.Context, orgID primitive.ObjectID, recipient string) error {
+	return s.repo.Delete(ctx, orgID, recipient)
+}
+
+// preferenceBlock returns why the preferences of the recipient of the notification do not allow
+// sending it now, or an empty string when it may be sent. When the notification is only outside
+// the hours of its category, the start of the next allowed window is returned as well.
+// Categories configured in preferences.bypassCategories are always sent. Notifications are
+// sent when the preferences cannot be read.
+func (s *NotificationService) preferenceBlock(ctx context.Context, msg *models.Notification) (string, *time.Time) {
+	category := notificationCategory(msg)
+	if s.preferences == nil || bypassesPreferences(category) {
+		return "", nil
+	}
+	preference, err := s.preferences.Find(ctx, msg.OrganizationID, msg.To)
+	if errors.Is(err, repo.ErrPreferenceNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Failed to read preferences for notification %s: %v", msg.ID.Hex(), err))
+		return "", nil
+	}
+	return preferenceBlock(preference, category, msg.Type, time.Now())
+}
+
+// notificationCategory returns the category of a notification; notifications without category are transactional
+func notificationCategory(msg *models.Notification) string {
+	if msg.Category == "" {
+		return models.CategoryTransactional
+	}
+	return msg.Category
+}
+
+// bypassesPreferences reports whether notifications of category are sent regardless of preferences
+func bypassesPreferences(category string) bool {
+	bypass := config.Config.Preferences.BypassCategories
+	if len(bypass) == 0 {
+		bypass = defaultBypassCategories
+	}
+	return slices.Contains(bypass, category)
+}
+
+// preferenceBlock returns why preference does not allow a notification of category to be sent on
+// channel at the given time, or an empty string when it is allowed. Outside the hours of the
+// category, the start of the next window is returned too.
+func preferenceBlock(preference *models.Preference, category, channel string, at time.Time) (string, *time.Time) {
+	categoryPreference, ok := preference.Categories[category]
+	if !ok {
+		return "", nil
+	}
+	if categoryPreference.OptOut {
+		return fmt.Sprintf("recipient opted out of %s notifications", category), nil
+	}
+	if len(categoryPreference.Channels) > 0 && !slices.Contains(categoryPreference.Channels, channel) {
+		return fmt.Sprintf("recipient does not accept %s notifications by %s", category, channel), nil
+	}
+	if hours := categoryPreference.Hours; hours != nil && !withinHours(hours, preference.TimeZone, at) {
+		reason := fmt.Sprintf("recipient accepts %s notifications between %s and %s (%s) only", category, hours.Start, hours.End, timeZoneName(preference.TimeZone))
+		return reason, nextWindowStart(hours, preference.TimeZone, at)
+	}
+	return "", nil
+}
+
+// withinHours reports whether at falls in the daily window of hours, in the given time zone
+func withinHours(hours *models.DeliveryHours, timeZone string, at time.Time) bool {
+	start, startErr := parseClock(hours.Start)
+	end, endErr := parseClock(hours.End)
+	location, err := time.LoadLocation(timeZone)
+	if startErr != nil || endErr != nil || err != nil {
+		return true // Invalid hours are rejected when preferences are saved
+	}
+
+	local := at.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	// The window spans midnight
+	return minute >= start || minute < end
+}
+
+// nextWindowStart returns the first start of the daily window of hours after at, in the given
+// time zone, or nil when the hours are invalid
+func nextWindowStart(hours *models.DeliveryHours, timeZone string, at time.Time) *time.Time {
+	start, startErr := parseClock(hours.Start)
+	location, err := time.LoadLocation(timeZone)
+	if startErr != nil || err != nil {
+		return nil
+	}
+	local := at.In(location)
+	for day := 0; day <= 1; day++ {
+		// time.Date moves starts falling into a DST gap forward
+		next := time.Date(local.Year(), local.Month(), local.Day()+day, start/60, start%60, 0, 0, location)
+		if next.After(at) {
+			return &next
+		}
+	}
+	return nil
+}
+
+// parseClock returns the minutes since midnight of an HH:MM time
+func parseClock(value string) (int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
+
+// timeZoneName returns the name of a time zone, UTC when empty
+func timeZoneName(timeZone string) string {
+	if timeZone == "" {
+		return "UTC"
+	}
+	return timeZone
+}
@@ -0,0 +1,61 @@
+/*
+service/preference_test.go
+Author: Akhil C
+Description: Tests of the validation of recipient preferences.
+*/
+
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidatePreference(t *testing.T) {
+	orgID, _ := primitive.ObjectIDFromHex("652f1c2e9b1e8a0001a1b2c3")
+	// preference returns valid preferences whose marketing category is changed by category
+	preference := func(category models.CategoryPreference) *models.Preference {
+		return &models.Preference{
+			OrganizationID: orgID,
+			Recipient:      "jane@example.com",
+			TimeZone:       "Europe/Paris",
+			Categories:     map[string]models.CategoryPreference{models.CategoryMarketing: category},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		preference *models.Preference
+		wantField  string // Field of the ValidationError, empty when the preferences are valid
+	}{
+		{name: "valid", preference: preference(models.CategoryPreference{Channels: []string{"email"}})},
+		{name: "hours", preference: preference(models.CategoryPreference{Hours: &models.DeliveryHours{Start: "09:00", End: "18:00"}})},
+		{name: "hours across midnight", preference: preference(models.CategoryPreference{Hours: &models.DeliveryHours{Start: "22:00", End: "02:00"}})},
+		{name: "missing organization", preference: &models.Preference{Recipient: "jane@example.com"}, wantField: "organization_id"},
+		{name: "missing recipient", preference: &models.Preference{OrganizationID: orgID, Recipient: " "}, wantField: "recipient"},
+		{name: "unknown time zone", preference: &models.Preference{OrganizationID: orgID, Recipient: "jane@example.com", TimeZone: "Mars/Olympus"}, wantField: "time_zone"},
+		{name: "unknown category", preference: &models.Preference{OrganizationID: orgID, Recipient: "jane@example.com", Categories: map[string]models.CategoryPreference{"news": {}}}, wantField: "categories"},
+		{name: "unknown channel", preference: preference(models.CategoryPreference{Channels: []string{"fax"}}), wantField: "categories"},
+		{name: "invalid hours", preference: preference(models.CategoryPreference{Hours: &models.DeliveryHours{Start: "9am", End: "18:00"}}), wantField: "categories"},
+		// An empty window would defer every notification of the category to its next start, forever
+		{name: "hours starting and ending at the same time", preference: preference(models.CategoryPreference{Hours: &models.DeliveryHours{Start: "09:00", End: "09:00"}}), wantField: "categories"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePreference(tt.preference)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Errorf("validatePreference() error = %v", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Field != tt.wantField {
+				t.Errorf("validatePreference() error = %v, want a ValidationError of %s", err, tt.wantField)
+			}
+		})
+	}
+}
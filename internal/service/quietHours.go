@@ -0,0 +1,190 @@
+/*
+service/quietHours.go
+Author: Akhil C
+Description: Service to manage the quiet hours of organizations and recipients and defer notifications sent inside them.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/notifications"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// quietDays maps the days of quiet windows to weekdays
+var quietDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// maxQuietWindowChain bounds the number of adjacent windows followed to find the end of quiet hours
+const maxQuietWindowChain = 14
+
+// QuietHoursService handles business logic for quiet hours
+type QuietHoursService struct {
+	repo *repo.QuietHours
+}
+
+// NewQuietHoursService creates a new instance of QuietHoursService
+func NewQuietHoursService(repo *repo.QuietHours) *QuietHoursService {
+	return &QuietHoursService{repo: repo}
+}
+
+// validateQuietHours checks the time zone, windows and channels of quiet hours
+func validateQuietHours(quietHours *models.QuietHours) error {
+	if quietHours.OrganizationID.IsZero() {
+		return &ValidationError{Field: "organization_id", Message: "organization ID is required"}
+	}
+	if _, err := time.LoadLocation(quietHours.TimeZone); err != nil {
+		return &ValidationError{Field: "time_zone", Message: "must be an IANA time zone (e.g. Europe/Paris)"}
+	}
+	if len(quietHours.Windows) == 0 {
+		return &ValidationError{Field: "windows", Message: "at least one window is required"}
+	}
+	for i, window := range quietHours.Windows {
+		start, startErr := parseClock(window.Start)
+		end, endErr := parseClock(window.End)
+		if startErr != nil || endErr != nil {
+			return &ValidationError{Field: "windows", Message: fmt.Sprintf("window %d must be given as HH:MM", i)}
+		}
+		if start == end {
+			return &ValidationError{Field: "windows", Message: fmt.Sprintf("window %d must not start and end at the same time", i)}
+		}
+		for _, day := range window.Days {
+			if _, ok := quietDays[strings.ToLower(day)]; !ok {
+				return &ValidationError{Field: "windows", Message: fmt.Sprintf("unknown day %q in window %d, expected mon, tue, wed, thu, fri, sat or sun", day, i)}
+			}
+		}
+	}
+	channels := []string{notifications.TypeEmail, notifications.TypeSMS, notifications.TypeWhatsApp}
+	for _, channel := range quietHours.Channels {
+		if !slices.Contains(channels, channel) {
+			return &ValidationError{Field: "channels", Message: fmt.Sprintf("unknown channel %q", channel)}
+		}
+	}
+	return nil
+}
+
+// SaveQuietHours stores the quiet hours of an organization, or of a recipient when one is given,
+// replacing the previous ones
+func (s *QuietHoursService) SaveQuietHours(ctx context.Context, quietHours *models.QuietHours) error {
+	if err := validateQuietHours(quietHours); err != nil {
+		return err
+	}
+	quietHours.Recipient = strings.TrimSpace(quietHours.Recipient)
+	return s.repo.Save(ctx, quietHours)
+}
+
+// ListQuietHours returns the quiet hours of an organization and its recipients
+func (s *QuietHoursService) ListQuietHours(ctx context.Context, orgID primitive.ObjectID) ([]*models.QuietHours, error) {
+	return s.repo.List(ctx, orgID)
+}
+
+// DeleteQuietHours removes the quiet hours of a recipient, or of the organization when recipient is empty
+func (s *QuietHoursService) DeleteQuietHours(ctx context.Context, orgID primitive.ObjectID, recipient string) error {
+	return s.repo.Delete(ctx, orgID, strings.TrimSpace(recipient))
+}
+
+// quietHoursEnd returns the end of the quiet hours the notification would be sent in, or nil when
+// it may be sent now. High priority notifications are not deferred unless the quiet hours say so.
+// Notifications are sent when the quiet hours cannot be read.
+func (s *NotificationService) quietHoursEnd(ctx context.Context, msg *models.Notification) *time.Time {
+	if s.quietHours == nil {
+		return nil
+	}
+	quietHours, err := s.quietHours.ForRecipient(ctx, msg.OrganizationID, msg.To)
+	if errors.Is(err, repo.ErrQuietHoursNotFound) {
+		return nil
+	}
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Failed to read quiet hours for notification %s: %v", msg.ID.Hex(), err))
+		return nil
+	}
+	if msg.Priority == "high" && !quietHours.DeferHighPriority {
+		return nil
+	}
+	if len(quietHours.Channels) > 0 && !slices.Contains(quietHours.Channels, msg.Type) {
+		return nil
+	}
+
+	// The time zone of the notification is the one of the recipient
+	timeZone := msg.TimeZone
+	if timeZone == "" {
+		timeZone = quietHours.TimeZone
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		location = time.UTC
+	}
+	return quietUntil(quietHours.Windows, time.Now().In(location))
+}
+
+// quietUntil returns the end of the windows at falls in, following windows that start before the
+// previous one ends, or nil when at is outside every window
+func quietUntil(windows []models.QuietWindow, at time.Time) *time.Time {
+	var until *time.Time
+	for range maxQuietWindowChain {
+		end := quietWindowEnd(windows, at)
+		if end == nil {
+			break
+		}
+		until, at = end, *end
+	}
+	return until
+}
+
+// quietWindowEnd returns the latest end of the windows at falls in, or nil when there is none
+func quietWindowEnd(windows []models.QuietWindow, at time.Time) *time.Time {
+	var latest *time.Time
+	year, month, day := at.Date()
+	for _, window := range windows {
+		start, startErr := parseClock(window.Start)
+		end, endErr := parseClock(window.End)
+		if startErr != nil || endErr != nil || start == end {
+			continue // Invalid windows are rejected when quiet hours are saved
+		}
+		// A window spanning midnight may have started the day before
+		for offset := -1; offset <= 0; offset++ {
+			windowStart := time.Date(year, month, day+offset, 0, start, 0, 0, at.Location())
+			if !quietDay(window, windowStart.Weekday()) {
+				continue
+			}
+			windowEnd := time.Date(year, month, day+offset, 0, end, 0, 0, at.Location())
+			if end < start {
+				windowEnd = time.Date(year, month, day+offset+1, 0, end, 0, 0, at.Location())
+			}
+			if !at.Before(windowStart) && at.Before(windowEnd) && (latest == nil || windowEnd.After(*latest)) {
+				latest = &windowEnd
+			}
+		}
+	}
+	return latest
+}
+
+// quietDay reports whether window applies to windows starting on weekday
+func quietDay(window models.QuietWindow, weekday time.Weekday) bool {
+	if len(window.Days) == 0 {
+		return true
+	}
+	for _, day := range window.Days {
+		if quietDay, ok := quietDays[strings.ToLower(day)]; ok && quietDay == weekday {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+/*
+service/quietHours_test.go
+Author: Akhil C
+Description: Tests of finding the end of the quiet windows a time falls in.
+*/
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+)
+
+func TestQuietUntil(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, paris)
+	}
+	night := []models.QuietWindow{{Start: "22:00", End: "07:00"}}
+
+	tests := []struct {
+		name    string
+		windows []models.QuietWindow
+		at      time.Time
+		want    *time.Time // nil when at is outside every window
+	}{
+		{name: "before midnight", windows: night, at: at(time.October, 16, 23, 30), want: ptr(at(time.October, 17, 7, 0))},
+		{name: "after midnight", windows: night, at: at(time.October, 17, 3, 0), want: ptr(at(time.October, 17, 7, 0))},
+		{name: "start is inclusive", windows: night, at: at(time.October, 16, 22, 0), want: ptr(at(time.October, 17, 7, 0))},
+		{name: "end is exclusive", windows: night, at: at(time.October, 17, 7, 0)},
+		{name: "outside the window", windows: night, at: at(time.October, 17, 12, 0)},
+		{name: "no windows", at: at(time.October, 17, 3, 0)},
+		// 2026-10-16 is a Friday: the window starting on Friday night covers Saturday morning
+		{name: "started on a listed day", windows: []models.QuietWindow{{Start: "22:00", End: "07:00", Days: []string{"fri"}}}, at: at(time.October, 17, 3, 0), want: ptr(at(time.October, 17, 7, 0))},
+		{name: "started on another day", windows: []models.QuietWindow{{Start: "22:00", End: "07:00", Days: []string{"Sat"}}}, at: at(time.October, 17, 3, 0)},
+		{name: "same day window", windows: []models.QuietWindow{{Start: "12:00", End: "14:00", Days: []string{"sat"}}}, at: at(time.October, 17, 13, 0), want: ptr(at(time.October, 17, 14, 0))},
+		{name: "chained windows", windows: []models.QuietWindow{night[0], {Start: "06:30", End: "09:00"}}, at: at(time.October, 16, 23, 0), want: ptr(at(time.October, 17, 9, 0))},
+		{name: "overlapping windows", windows: []models.QuietWindow{night[0], {Start: "21:00", End: "06:00"}}, at: at(time.October, 16, 21, 30), want: ptr(at(time.October, 17, 7, 0))},
+		{name: "every day quiet", windows: []models.QuietWindow{{Start: "00:00", End: "12:00"}, {Start: "12:00", End: "00:00"}}, at: at(time.October, 17, 9, 0), want: ptr(at(time.October, 24, 0, 0))},
+		// Clocks go back from 03:00 to 02:00 on 2026-10-25, so the window lasts four hours
+		{name: "end of daylight saving time", windows: []models.QuietWindow{{Start: "01:00", End: "04:00"}}, at: at(time.October, 25, 1, 30), want: ptr(at(time.October, 25, 4, 0))},
+		// Clocks go forward from 02:00 to 03:00 on 2026-03-29, so the window lasts two hours
+		{name: "start of daylight saving time", windows: []models.QuietWindow{{Start: "01:00", End: "04:00"}}, at: at(time.March, 29, 1, 30), want: ptr(at(time.March, 29, 4, 0))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := quietUntil(tt.windows, tt.at)
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("quietUntil() = %v, want nil", *got)
+			case tt.want != nil && got == nil:
+				t.Errorf("quietUntil() = nil, want %v", *tt.want)
+			case tt.want != nil && !got.Equal(*tt.want):
+				t.Errorf("quietUntil() = %v, want %v", *got, *tt.want)
+			}
+		})
+	}
+
+	// The windows measured in elapsed time, not wall clock time
+	windows := []models.QuietWindow{{Start: "01:00", End: "04:00"}}
+	for _, tt := range []struct {
+		at   time.Time
+		want time.Duration
+	}{
+		{at(time.October, 25, 1, 0), 4 * time.Hour},
+		{at(time.March, 29, 1, 0), 2 * time.Hour},
+	} {
+		if got := quietUntil(windows, tt.at); got == nil || got.Sub(tt.at) != tt.want {
+			t.Errorf("quiet window from %v lasts %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,97 @@
+/*
+service/rateLimit.go
+Author: Akhil C
+Description: Defers notifications over the rate limits of their channel, organization or provider instead of dropping them.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/notifications"
+	"github.com/akhilckenshi/notification/internal/ratelimit"
+	"github.com/akhilckenshi/notification/pkg/logger"
+)
+
+// maxRateLimitWait is the longest a worker waits for a rate limit; notifications limited for
+// longer are scheduled for when the limit allows them
+const maxRateLimitWait = time.Second
+
+// rateLimitKeys returns the channel and organization limits applying to a notification
+func rateLimitKeys(msg *models.Notification) []ratelimit.Key {
+	return []ratelimit.Key{
+		{Scope: ratelimit.ScopeChannel, Name: msg.Type},
+		{Scope: ratelimit.ScopeOrganization, Name: msg.OrganizationID.Hex()},
+	}
+}
+
+// deferRateLimited takes a token of the channel and organization limits of the notification,
+// waiting while they are reached, and reports whether the notification must not be sent now.
+// Notifications limited for longer than maxRateLimitWait are scheduled instead.
+func (s *NotificationService) deferRateLimited(ctx context.Context, msg *models.Notification) bool {
+	if s.limiter == nil {
+		return false
+	}
+	for {
+		var limited *ratelimit.LimitedError
+		if err := s.limiter.Take(rateLimitKeys(msg)...); !errors.As(err, &limited) {
+			return false
+		}
+		if limited.RetryAfter > maxRateLimitWait && s.schedules != nil {
+			logger.Log.Info(fmt.Sprintf("Deferring notification %s: %v", msg.ID.Hex(), limited))
+			held, _ := s.schedule(ctx, msg, time.Now().Add(limited.RetryAfter), limited.Error())
+			return held
+		}
+		if !waitRateLimit(ctx, limited) {
+			return true // The notification stays queued
+		}
+	}
+}
+
+// rateLimited returns the rate limit that prevented an attempt when every provider tried was over
+// its limit, so the attempt was not made at all: the limit lifting first is returned. nil is
+// returned when a provider did try to send.
+func rateLimited(err error) *ratelimit.LimitedError {
+	var failed *notifications.ProvidersFailedError
+	if errors.As(err, &failed) {
+		var soonest *ratelimit.LimitedError
+		for _, providerErr := range failed.Errors {
+			limited := rateLimited(providerErr)
+			if limited == nil {
+				return nil
+			}
+			if soonest == nil || limited.RetryAfter < soonest.RetryAfter {
+				soonest = limited
+			}
+		}
+		return soonest
+	}
+	var limited *ratelimit.LimitedError
+	if errors.As(err, &limited) {
+		return limited
+	}
+	return nil
+}
+
+// waitRateLimit waits until the limit allows another message and reports whether ctx is still active
+func waitRateLimit(ctx context.Context, limited *ratelimit.LimitedError) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(limited.RetryAfter):
+		return true
+	}
+}
+
+// RateLimitStats returns the utilization of the rate limits in use
+func (s *NotificationService) RateLimitStats() []ratelimit.Stats {
+	if s.limiter == nil {
+		return []ratelimit.Stats{}
+	}
+	return s.limiter.Stats()
+}
@@ -0,0 +1,76 @@
+/*
+service/recovery.go
+Author: Akhil C
+Description: Queues again the notifications left waiting to be sent by an instance that stopped before sending them.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/akhilckenshi/notification/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recoveryBatch bounds the notifications read per query of a recovery sweep
+const recoveryBatch = 500
+
+// Recovery sweeps run every recoveryInterval and pick up the notifications unchanged for
+// recoveryGrace, long enough for the instance that queued them to have leased them
+const (
+	recoveryInterval = time.Minute
+	recoveryGrace    = notificationLease
+)
+
+// runRecovery recovers the pending notifications left by a previous run, then sweeps for pending
+// notifications of instances that stopped (e.g. after the scheduler released them) every
+// recoveryInterval, until ctx is cancelled
+func (s *NotificationService) runRecovery(ctx context.Context) {
+	s.recoverPending(ctx, time.Now())
+
+	ticker := time.NewTicker(recoveryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.recoverPending(ctx, time.Now().Add(-recoveryGrace))
+		}
+	}
+}
+
+// recoverPending queues the notifications still waiting to be sent that no worker holds, e.g.
+// because the instance that stored them stopped before sending them. Only notifications
+// unchanged since updatedBefore are recovered. A notification also queued elsewhere meanwhile is
+// still sent once, by the worker that leases it first.
+func (s *NotificationService) recoverPending(ctx context.Context, updatedBefore time.Time) {
+	if s.repo == nil {
+		return
+	}
+	var after primitive.ObjectID
+	recovered := 0
+	for ctx.Err() == nil {
+		msgs, err := s.repo.ListPending(ctx, updatedBefore, after, recoveryBatch)
+		if err != nil {
+			logger.Log.Error(err.Error())
+			break
+		}
+		for _, msg := range msgs {
+			if err := s.enqueue(ctx, msg, nil, nil); err != nil {
+				return // Stopped; the remaining notifications are recovered by the next sweep
+			}
+			recovered++
+		}
+		if len(msgs) < recoveryBatch {
+			break
+		}
+		after = msgs[len(msgs)-1].ID
+	}
+	if recovered > 0 {
+		logger.Log.Info(fmt.Sprintf("Recovered %d pending notifications", recovered))
+	}
+}
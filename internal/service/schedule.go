@@ -0,0 +1,300 @@
+/*
+service/schedule.go
+Author: Akhil C
+Description: Holds notifications until their send time and releases them from a scheduler loop that is safe to run on several replicas.
+*/
+
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	config "github.com/akhilckenshi/notification/pkg/settings"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Scheduler defaults, used when scheduler.pollInterval or scheduler.leaseSeconds are not configured
+const (
+	defaultSchedulerPollInterval = 5 * time.Second
+	defaultScheduleLease         = time.Minute
+)
+
+// maxScheduleClaims bounds the number of schedules claimed in a single poll
+const maxScheduleClaims = 100
+
+// ErrNotScheduled is returned when cancelling or rescheduling a notification that is not (or no longer) scheduled
+var ErrNotScheduled = errors.New("notification is not scheduled")
+
+// localSendAtLayouts are the accepted formats of a send time without offset
+var localSendAtLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// parseSendAt returns the time of an RFC 3339 send time, or the send time itself when it is a
+// local date and time to resolve in the time zone of the recipient
+func parseSendAt(value string) (*time.Time, string, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return &at, "", nil
+	}
+	if _, err := parseLocalSendAt(value, time.UTC); err != nil {
+		return nil, "", err
+	}
+	return nil, value, nil
+}
+
+// parseLocalSendAt returns the time of a local date and time in location
+func parseLocalSendAt(value string, location *time.Location) (time.Time, error) {
+	for _, layout := range localSendAtLayouts {
+		if at, err := time.ParseInLocation(layout, value, location); err == nil {
+			return at, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("send time %q is neither RFC 3339 nor a local date and time (2006-01-02T15:04)", value)
+}
+
+// sendTime returns the time the notification is sent at, or nil when it is sent right away. A local
+// send time is resolved in the time zone of the notification, or else of the recipient preferences.
+func (s *NotificationService) sendTime(ctx context.Context, msg *models.Notification) (*time.Time, error) {
+	if msg.SendAt != nil || msg.LocalSendAt == "" {
+		return msg.SendAt, nil
+	}
+
+	timeZone := msg.TimeZone
+	if timeZone == "" && s.preferences != nil {
+		if preference, err := s.preferences.Find(ctx, msg.OrganizationID, msg.To); err == nil {
+			timeZone = preference.TimeZone
+		}
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", timeZone)
+	}
+	at, err := parseLocalSendAt(msg.LocalSendAt, location)
+	if err != nil {
+		return nil, err
+	}
+	msg.SendAt = &at
+	return &at, nil
+}
+
+// holdUntilSendAt schedules a queued notification whose send time has not been reached yet and
+// reports whether the notification must not be sent now
+func (s *NotificationService) holdUntilSendAt(ctx context.Context, msg *models.Notification) bool {
+	sendAt, err := s.sendTime(ctx, msg)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Rejecting notification %s: %v", msg.ID.Hex(), err))
+		s.transition(ctx, msg, models.StatusFailed, err.Error(), nil)
+		s.publishDeadLetter(msg, err)
+		return true
+	}
+	if sendAt == nil || !sendAt.After(time.Now()) {
+		return false
+	}
+	held, _ := s.schedule(ctx, msg, *sendAt, fmt.Sprintf("scheduled for %s", sendAt.Format(time.RFC3339)))
+	return held
+}
+
+// schedule holds a queued or retrying notification until at and reports whether it must not be
+// sent now. Without schedule store the notification is not held and is sent right away. When the
+// schedule cannot be stored the error is returned and the notification is not sent now either: it
+// stays pending and is dispatched again by the recovery sweep, unless the database rejected the
+// schedule, which fails the notification.
+func (s *NotificationService) schedule(ctx context.Context, msg *models.Notification, at time.Time, reason string) (bool, error) {
+	if s.schedules == nil {
+		logger.Log.Warn(fmt.Sprintf("Sending notification %s now: no schedule store to hold it until %s", msg.ID.Hex(), at.Format(time.RFC3339)))
+		return false, nil
+	}
+
+	// The schedule is stored first, so a scheduled notification always has a schedule
+	schedule := &models.Schedule{NotificationID: msg.ID, OrganizationID: msg.OrganizationID, SendAt: at}
+	if err := s.schedules.Add(ctx, schedule); err != nil {
+		if errors.Is(err, repo.ErrScheduleRejected) {
+			s.transition(ctx, msg, models.StatusFailed, err.Error(), nil)
+			s.publishDeadLetter(msg, err)
+			return true, err
+		}
+		logger.Log.Warn(fmt.Sprintf("Leaving notification %s %s for the recovery sweep: %v", msg.ID.Hex(), msg.Status, err))
+		return true, err
+	}
+	msg.SendAt = &at
+	if err := s.transition(ctx, msg, models.StatusScheduled, reason, bson.M{"send_at": at}); err != nil {
+		// The notification changed meanwhile and is not ours to send
+		if err := s.schedules.DeleteByNotification(ctx, msg.ID); err != nil {
+			logger.Log.Error(err.Error())
+		}
+	}
+	return true, nil
+}
+
+// RunScheduler releases scheduled notifications once their send time is reached, until ctx is
+// cancelled. Due schedules are claimed with a lease, so the scheduler can run on every replica.
+func (s *NotificationService) RunScheduler(ctx context.Context) {
+	if s.schedules == nil {
+		logger.Log.Warn("Scheduler disabled: no schedule store")
+		return
+	}
+
+	interval, lease := defaultSchedulerPollInterval, defaultScheduleLease
+	if config.Config.Scheduler.PollInterval > 0 {
+		interval = time.Duration(config.Config.Scheduler.PollInterval) * time.Second
+	}
+	if config.Config.Scheduler.LeaseSeconds > 0 {
+		lease = time.Duration(config.Config.Scheduler.LeaseSeconds) * time.Second
+	}
+	owner := schedulerID()
+	logger.Log.Info(fmt.Sprintf("Scheduler %s started", owner))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.releaseDue(ctx, owner, lease)
+		select {
+		case <-ctx.Done():
+			logger.Log.Info(fmt.Sprintf("Scheduler %s stopped", owner))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// releaseDue claims the due schedules and dispatches their notifications
+func (s *NotificationService) releaseDue(ctx context.Context, owner string, lease time.Duration) {
+	var released []*models.Notification
+	for i := 0; i < maxScheduleClaims && ctx.Err() == nil; i++ {
+		schedule, err := s.schedules.ClaimDue(ctx, owner, lease)
+		if err != nil {
+			logger.Log.Error(err.Error())
+			break
+		}
+		if schedule == nil {
+			break
+		}
+		if msg := s.release(ctx, schedule); msg != nil {
+			released = append(released, msg)
+		}
+	}
+	if len(released) > 0 {
+		s.goBackground(func() { s.dispatchAll(ctx, released) })
+	}
+}
+
+// release moves the notification of a claimed schedule back to queued and removes the schedule.
+// It returns nil when the notification must not be sent (e.g. it was cancelled). A released
+// notification that is never dispatched, e.g. because the instance stopped, stays queued and is
+// recovered by the recovery sweep.
+func (s *NotificationService) release(ctx context.Context, schedule *models.Schedule) *models.Notification {
+	msg, err := s.repo.FindByID(ctx, schedule.NotificationID)
+	switch {
+	case errors.Is(err, repo.ErrNotificationNotFound):
+		msg = nil
+	case err != nil:
+		logger.Log.Error(err.Error()) // The schedule is claimed again once the lease expires
+		return nil
+	case msg.Status != models.StatusScheduled:
+		msg = nil // Cancelled meanwhile
+	default:
+		if err := s.transition(ctx, msg, models.StatusQueued, "send time reached", nil); err != nil {
+			if !errors.Is(err, repo.ErrStaleStatus) {
+				return nil // The schedule is claimed again once the lease expires
+			}
+			msg = nil
+		}
+	}
+
+	if err := s.schedules.Delete(ctx, schedule.ID); err != nil {
+		logger.Log.Error(err.Error())
+	}
+	return msg
+}
+
+// CancelNotification cancels a scheduled notification of an organization.
+// ErrNotScheduled is returned when the notification is not scheduled anymore.
+func (s *NotificationService) CancelNotification(ctx context.Context, orgID, id primitive.ObjectID) (*models.Notification, error) {
+	msg, err := s.scheduledNotification(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.transition(ctx, msg, models.StatusCancelled, "cancelled by request", nil); err != nil {
+		if errors.Is(err, repo.ErrStaleStatus) {
+			return nil, ErrNotScheduled
+		}
+		return nil, err
+	}
+	if s.schedules != nil {
+		if err := s.schedules.DeleteByNotification(ctx, msg.ID); err != nil {
+			logger.Log.Error(err.Error()) // The scheduler drops schedules of cancelled notifications
+		}
+	}
+	return msg, nil
+}
+
+// RescheduleNotification moves a scheduled notification of an organization to a new send time, given
+// like the send_at of a notification. A time zone replaces the one of the notification when given.
+func (s *NotificationService) RescheduleNotification(ctx context.Context, orgID, id primitive.ObjectID, sendAt, timeZone string) (*models.Notification, error) {
+	absolute, local, err := parseSendAt(sendAt)
+	if err != nil {
+		return nil, &ValidationError{Field: "send_at", Message: "must be an RFC 3339 time or a local date and time (2006-01-02T15:04)"}
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return nil, &ValidationError{Field: "time_zone", Message: "must be an IANA time zone (e.g. Europe/Paris)"}
+	}
+
+	msg, err := s.scheduledNotification(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	msg.SendAt, msg.LocalSendAt = absolute, local
+	if timeZone != "" {
+		msg.TimeZone = timeZone
+	}
+	at, err := s.sendTime(ctx, msg)
+	if err != nil {
+		return nil, &ValidationError{Field: "send_at", Message: err.Error()}
+	}
+	if !at.After(time.Now()) {
+		return nil, &ValidationError{Field: "send_at", Message: "must be in the future"}
+	}
+
+	if err := s.schedules.Reschedule(ctx, msg.ID, *at); err != nil {
+		if errors.Is(err, repo.ErrScheduleNotFound) {
+			return nil, ErrNotScheduled
+		}
+		return nil, err
+	}
+	fields := bson.M{"send_at": at, "local_send_at": msg.LocalSendAt, "time_zone": msg.TimeZone}
+	if err := s.repo.UpdateFields(ctx, msg.ID, fields); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// scheduledNotification returns a notification of an organization that is still scheduled
+func (s *NotificationService) scheduledNotification(ctx context.Context, orgID, id primitive.ObjectID) (*models.Notification, error) {
+	msg, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if msg.OrganizationID != orgID {
+		return nil, repo.ErrNotificationNotFound
+	}
+	if msg.Status != models.StatusScheduled || s.schedules == nil {
+		return nil, ErrNotScheduled
+	}
+	return msg, nil
+}
+
+// schedulerID identifies the scheduler of this process in schedule leases
+func schedulerID() string {
+	hostname, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
@@ -0,0 +1,106 @@
+/*
+service/suppression.go
+Author: Akhil C
+Description: Service to manage the recipients that notifications must not be sent to.
+*/
+
+package service
+
+import (
+	"context"
+	"net/mail"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/notifications"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reasons accepted on a suppression
+var validSuppressionReasons = map[string]bool{
+	models.SuppressionBounce:       true,
+	models.SuppressionComplaint:    true,
+	models.SuppressionUnsubscribed: true,
+	models.SuppressionManual:       true,
+}
+
+// SuppressionService handles business logic for suppressed recipients
+type SuppressionService struct {
+	repo *repo.Suppression
+}
+
+// NewSuppressionService creates a new instance of SuppressionService
+func NewSuppressionService(repo *repo.Suppression) *SuppressionService {
+	return &SuppressionService{repo: repo}
+}
+
+// validateSuppression checks the fields of a suppression added or changed through the API
+func validateSuppression(suppression *models.Suppression) error {
+	if suppression.OrganizationID.IsZero() {
+		return &ValidationError{Field: "organization_id", Message: "organization ID is required"}
+	}
+	if suppression.Reason == "" {
+		suppression.Reason = models.SuppressionManual
+	}
+	if !validSuppressionReasons[suppression.Reason] {
+		return &ValidationError{Field: "reason", Message: "must be one of bounce, complaint, unsubscribed, manual"}
+	}
+	if suppression.ExpiresAt != nil && !suppression.ExpiresAt.After(time.Now()) {
+		return &ValidationError{Field: "expires_at", Message: "must be in the future"}
+	}
+	return nil
+}
+
+// validateRecipient checks the recipient of a suppression against its channel
+func validateRecipient(suppression *models.Suppression) error {
+	switch suppression.Channel {
+	case notifications.TypeEmail:
+		if _, err := mail.ParseAddress(suppression.Recipient); err != nil {
+			return &ValidationError{Field: "recipient", Message: "must be a valid email address"}
+		}
+	case notifications.TypeWhatsApp, notifications.TypeSMS:
+		if !phoneNumberPattern.MatchString(models.NormalizeRecipient(suppression.Recipient)) {
+			return &ValidationError{Field: "recipient", Message: "must be a phone number in E.164 format"}
+		}
+	default:
+		return &ValidationError{Field: "channel", Message: "must be one of email, sms, whatsapp"}
+	}
+	return nil
+}
+
+// AddSuppression suppresses a recipient. Suppressing an already suppressed recipient replaces
+// the reason, detail and expiry of the existing suppression.
+func (s *SuppressionService) AddSuppression(ctx context.Context, suppression *models.Suppression) error {
+	if err := validateSuppression(suppression); err != nil {
+		return err
+	}
+	if err := validateRecipient(suppression); err != nil {
+		return err
+	}
+	suppression.ID = primitive.NilObjectID
+	return s.repo.Add(ctx, suppression)
+}
+
+// GetSuppression returns a suppression of an organization
+func (s *SuppressionService) GetSuppression(ctx context.Context, orgID, id primitive.ObjectID) (*models.Suppression, error) {
+	return s.repo.Find(ctx, orgID, id)
+}
+
+// ListSuppressions returns the suppressions of an organization, optionally for a channel or recipient only
+func (s *SuppressionService) ListSuppressions(ctx context.Context, orgID primitive.ObjectID, channel, recipient string) ([]*models.Suppression, error) {
+	return s.repo.List(ctx, orgID, channel, recipient)
+}
+
+// UpdateSuppression changes the reason, detail and expiry of a suppression
+func (s *SuppressionService) UpdateSuppression(ctx context.Context, suppression *models.Suppression) error {
+	if err := validateSuppression(suppression); err != nil {
+		return err
+	}
+	return s.repo.Update(ctx, suppression)
+}
+
+// DeleteSuppression lifts a suppression so the recipient can be sent to again
+func (s *SuppressionService) DeleteSuppression(ctx context.Context, orgID, id primitive.ObjectID) error {
+	return s.repo.Delete(ctx, orgID, id)
+}
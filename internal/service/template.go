@@ -0,0 +1,121 @@
+/*
+service/template.go
+Author: Akhil C
+Description: Service to manage versioned notification templates and render them.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"github.com/akhilckenshi/notification/internal/templates"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrTemplateExists is returned when creating a template whose ID is already in use
+var ErrTemplateExists = errors.New("template already exists")
+
+// TemplateService handles business logic for notification templates
+type TemplateService struct {
+	repo *repo.Template
+}
+
+// NewTemplateService creates a new instance of TemplateService
+func NewTemplateService(repo *repo.Template) *TemplateService {
+	return &TemplateService{repo: repo}
+}
+
+// validateTemplate checks the fields and the template syntax of a template version
+func validateTemplate(template *models.Template) error {
+	if template.OrganizationID.IsZero() {
+		return &ValidationError{Field: "organization_id", Message: "organization ID is required"}
+	}
+	if strings.TrimSpace(template.TemplateID) == "" {
+		return &ValidationError{Field: "template_id", Message: "template ID is required"}
+	}
+	if err := templates.Validate(template); err != nil {
+		return &ValidationError{Field: "variants", Message: err.Error()}
+	}
+	return nil
+}
+
+// CreateTemplate stores the first version of a new template
+func (s *TemplateService) CreateTemplate(ctx context.Context, template *models.Template) error {
+	if err := validateTemplate(template); err != nil {
+		return err
+	}
+	if _, err := s.repo.FindVersion(ctx, template.OrganizationID, template.TemplateID, 0); err == nil {
+		return ErrTemplateExists
+	} else if !errors.Is(err, repo.ErrTemplateNotFound) {
+		return err
+	}
+	return s.storeNextVersion(ctx, template)
+}
+
+// UpdateTemplate stores the template as a new version of an existing template
+func (s *TemplateService) UpdateTemplate(ctx context.Context, template *models.Template) error {
+	if err := validateTemplate(template); err != nil {
+		return err
+	}
+	if _, err := s.repo.FindVersion(ctx, template.OrganizationID, template.TemplateID, 0); err != nil {
+		return err
+	}
+	return s.storeNextVersion(ctx, template)
+}
+
+// storeNextVersion stores the template with the version following the highest stored one
+func (s *TemplateService) storeNextVersion(ctx context.Context, template *models.Template) error {
+	latest, err := s.repo.MaxVersion(ctx, template.OrganizationID, template.TemplateID)
+	if err != nil {
+		return err
+	}
+	template.ID = primitive.NilObjectID
+	template.Version = latest + 1
+	template.Active = true
+	if err := s.repo.CreateVersion(ctx, template); err != nil {
+		if errors.Is(err, repo.ErrTemplateVersionExists) {
+			return fmt.Errorf("template %s was modified concurrently, please retry", template.TemplateID)
+		}
+		return err
+	}
+	return nil
+}
+
+// GetTemplate returns a version of a template, or its latest version when version is 0
+func (s *TemplateService) GetTemplate(ctx context.Context, orgID primitive.ObjectID, templateID string, version int) (*models.Template, error) {
+	return s.repo.FindVersion(ctx, orgID, templateID, version)
+}
+
+// ListTemplates returns the latest version of every template of an organization
+func (s *TemplateService) ListTemplates(ctx context.Context, orgID primitive.ObjectID) ([]*models.Template, error) {
+	return s.repo.ListLatest(ctx, orgID)
+}
+
+// ListTemplateVersions returns every version of a template, newest first
+func (s *TemplateService) ListTemplateVersions(ctx context.Context, orgID primitive.ObjectID, templateID string) ([]*models.Template, error) {
+	return s.repo.ListVersions(ctx, orgID, templateID)
+}
+
+// DeleteTemplate deletes every version of a template
+func (s *TemplateService) DeleteTemplate(ctx context.Context, orgID primitive.ObjectID, templateID string) error {
+	return s.repo.Deactivate(ctx, orgID, templateID)
+}
+
+// RenderTemplate renders the channel variant of a template version best matching locale with data
+func (s *TemplateService) RenderTemplate(ctx context.Context, orgID primitive.ObjectID, templateID string, version int, channel, locale string, data map[string]any) (*models.Template, templates.Rendered, error) {
+	template, err := s.repo.FindVersion(ctx, orgID, templateID, version)
+	if err != nil {
+		return nil, templates.Rendered{}, err
+	}
+	rendered, err := templates.Render(template, channel, locale, data)
+	if err != nil {
+		return nil, templates.Rendered{}, err
+	}
+	return template, rendered, nil
+}
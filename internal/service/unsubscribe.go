@@ -0,0 +1,36 @@
+/*
+service/unsubscribe.go
+Author: Akhil C
+Description: Applies the one-click unsubscribe links of marketing emails to the preferences of their recipients.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/akhilckenshi/notification/internal/unsubscribe"
+	"github.com/akhilckenshi/notification/pkg/logger"
+	config "github.com/akhilckenshi/notification/pkg/settings"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Unsubscribe opts the recipient of an unsubscribe token out of the category it was issued for.
+// unsubscribe.ErrInvalidToken is returned for tokens that were not issued by the service.
+func (s *PreferenceService) Unsubscribe(ctx context.Context, token string) (unsubscribe.Claims, error) {
+	claims, err := unsubscribe.Verify([]byte(config.Config.UnsubscribeSecret), token)
+	if err != nil {
+		return claims, err
+	}
+	orgID, err := primitive.ObjectIDFromHex(claims.OrganizationID)
+	if err != nil || !validCategories[claims.Category] {
+		return claims, unsubscribe.ErrInvalidToken
+	}
+
+	if err := s.repo.OptOut(ctx, orgID, claims.Recipient, claims.Category); err != nil {
+		return claims, err
+	}
+	logger.Log.Info(fmt.Sprintf("Recipient %s of organization %s unsubscribed from %s notifications", claims.Recipient, claims.OrganizationID, claims.Category))
+	return claims, nil
+}
@@ -0,0 +1,155 @@
+/*
+service/validation.go
+Author: Akhil C
+Description: Validation of notification payloads submitted to the service.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/models"
+	"github.com/akhilckenshi/notification/internal/notifications"
+	"github.com/akhilckenshi/notification/internal/repo"
+	"github.com/akhilckenshi/notification/internal/templates"
+)
+
+// phoneNumberPattern matches phone numbers in E.164 format, with an optional leading '+'
+var phoneNumberPattern = regexp.MustCompile(`^\+?[1-9]\d{6,14}$`)
+
+// Priorities accepted on a notification
+var validPriorities = map[string]bool{"": true, "high": true, "medium": true, "low": true}
+
+// ValidationError describes an invalid field of a submitted notification
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Message)
+}
+
+// validateNotifier checks that a notification payload can be dispatched
+func (s *NotificationService) validateNotifier(ctx context.Context, notifier *models.Notifier) error {
+	if notifier.OrganizationID.IsZero() {
+		return &ValidationError{Field: "organization_id", Message: "organization ID is required"}
+	}
+	if _, err := s.registry.Get(notifier.Type); err != nil {
+		return &ValidationError{Field: "type", Message: fmt.Sprintf("must be one of %s", strings.Join(s.registry.Types(), ", "))}
+	}
+	if !validPriorities[notifier.Priority] {
+		return &ValidationError{Field: "priority", Message: "must be one of high, medium, low"}
+	}
+	if notifier.Category != "" && !validCategories[notifier.Category] {
+		return &ValidationError{Field: "category", Message: "must be one of transactional, security, billing, marketing"}
+	}
+	if notifier.SendAt != "" {
+		if _, _, err := parseSendAt(notifier.SendAt); err != nil {
+			return &ValidationError{Field: "send_at", Message: "must be an RFC 3339 time or a local date and time (2006-01-02T15:04)"}
+		}
+	}
+	if _, err := time.LoadLocation(notifier.TimeZone); err != nil {
+		return &ValidationError{Field: "time_zone", Message: "must be an IANA time zone (e.g. Europe/Paris)"}
+	}
+	if strings.TrimSpace(notifier.To) == "" {
+		return &ValidationError{Field: "to", Message: "recipient is required"}
+	}
+
+	switch notifier.Type {
+	case notifications.TypeEmail:
+		if _, err := mail.ParseAddress(notifier.To); err != nil {
+			return &ValidationError{Field: "to", Message: "must be a valid email address"}
+		}
+		if err := validateEmailExtras(notifier); err != nil {
+			return err
+		}
+	case notifications.TypeWhatsApp, notifications.TypeSMS:
+		if !phoneNumberPattern.MatchString(notifier.To) {
+			return &ValidationError{Field: "to", Message: "must be a phone number in E.164 format"}
+		}
+	}
+
+	// Template based notifications get their content when they are sent
+	if notifier.TemplateID != "" {
+		template, err := s.templates.GetTemplate(ctx, notifier.OrganizationID, notifier.TemplateID, notifier.TemplateVersion)
+		if errors.Is(err, repo.ErrTemplateNotFound) {
+			return &ValidationError{Field: "template_id", Message: "template not found"}
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := templates.ResolveVariant(template, notifier.Type, notifier.Locale); !ok {
+			return &ValidationError{Field: "template_id", Message: fmt.Sprintf("template has no %s variant for locale %q", notifier.Type, notifier.Locale)}
+		}
+		return nil
+	}
+
+	if notifier.Type == notifications.TypeWhatsApp {
+		return validateWhatsAppContent(notifier)
+	}
+	if strings.TrimSpace(notifier.Message) == "" {
+		return &ValidationError{Field: "message", Message: "message is required"}
+	}
+	if notifier.Type == notifications.TypeSMS {
+		_, err := notifications.SMSBody(&models.Notification{Subject: notifier.Subject, Message: notifier.Message, PlainText: notifier.PlainText})
+		if err != nil {
+			return &ValidationError{Field: "message", Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// validateEmailExtras checks the copy recipients, reply-to address and attachments of an email
+func validateEmailExtras(notifier *models.Notifier) error {
+	for field, addresses := range map[string][]string{"cc": notifier.Cc, "bcc": notifier.Bcc} {
+		for _, address := range addresses {
+			if _, err := mail.ParseAddress(address); err != nil {
+				return &ValidationError{Field: field, Message: fmt.Sprintf("%q is not a valid email address", address)}
+			}
+		}
+	}
+	if notifier.ReplyTo != "" {
+		if _, err := mail.ParseAddress(notifier.ReplyTo); err != nil {
+			return &ValidationError{Field: "reply_to", Message: "must be a valid email address"}
+		}
+	}
+	for _, attachment := range notifier.Attachments {
+		if attachment.Filename == "" {
+			return &ValidationError{Field: "attachments", Message: "filename is required"}
+		}
+		if len(attachment.Content) == 0 {
+			return &ValidationError{Field: "attachments", Message: fmt.Sprintf("%s has no content", attachment.Filename)}
+		}
+	}
+	return nil
+}
+
+// contentSIDPattern matches the SID of a Twilio content template
+var contentSIDPattern = regexp.MustCompile(`^HX[0-9a-fA-F]{32}$`)
+
+// validateWhatsAppContent checks that a WhatsApp notification has a free-form message, media or
+// a content template, and that its media URLs are public http(s) URLs
+func validateWhatsAppContent(notifier *models.Notifier) error {
+	if notifier.ContentSID != "" && !contentSIDPattern.MatchString(notifier.ContentSID) {
+		return &ValidationError{Field: "content_sid", Message: "must be a content template SID (HX...)"}
+	}
+	if strings.TrimSpace(notifier.Message) == "" && len(notifier.MediaURLs) == 0 && notifier.ContentSID == "" {
+		return &ValidationError{Field: "message", Message: "message, media_urls or content_sid is required"}
+	}
+	for _, mediaURL := range notifier.MediaURLs {
+		parsed, err := url.Parse(mediaURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return &ValidationError{Field: "media_urls", Message: fmt.Sprintf("%q is not an http(s) URL", mediaURL)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+/*
+service/whatsapp.go
+Author: Akhil C
+Description: Handles messages received on the WhatsApp number to track the session window of each recipient.
+*/
+
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/akhilckenshi/notification/internal/repo"
+)
+
+// WhatsAppService records inbound WhatsApp messages
+type WhatsAppService struct {
+	sessions *repo.WhatsAppSession
+}
+
+func NewWhatsAppService(sessions *repo.WhatsAppSession) *WhatsAppService {
+	return &WhatsAppService{sessions: sessions}
+}
+
+// RecordInbound opens the session window of the sender of an inbound message. Messages
+// received on other channels (e.g. SMS) are ignored.
+func (s *WhatsAppService) RecordInbound(ctx context.Context, from string) error {
+	if !strings.HasPrefix(from, "whatsapp:") {
+		return &ValidationError{Field: "From", Message: "not a WhatsApp sender"}
+	}
+	return s.sessions.RecordInbound(ctx, from, time.Now())
+}
@@ -0,0 +1,179 @@
+/*
+templates/locale.go
+Author: Akhil C
+Description: Locale fallback resolution for template variants and locale-aware formatting helpers available inside templates.
+*/
+
+package templates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// dateLayouts holds the numeric date layout of a locale or language; ISO 8601 is used otherwise
+var dateLayouts = map[string]string{
+	"en-US": "01/02/2006",
+	"en":    "02/01/2006",
+	"de":    "02.01.2006",
+	"fr":    "02/01/2006",
+	"es":    "02/01/2006",
+	"it":    "02/01/2006",
+	"pt":    "02/01/2006",
+	"nl":    "02-01-2006",
+	"ja":    "2006/01/02",
+	"zh":    "2006/01/02",
+}
+
+// NormalizeLocale returns the canonical form of a locale code (e.g. "pt_br" becomes "pt-BR")
+func NormalizeLocale(locale string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+	for i, part := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(part)
+		case len(part) == 2:
+			parts[i] = strings.ToUpper(part) // Region
+		case len(part) == 4:
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:]) // Script
+		default:
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// LocaleChain returns the locales tried, in order, when resolving a variant for locale:
+// the locale itself, its less specific parents, the template default locale (and its
+// parents) and finally "" for variants without a locale. For example "pt-BR" with
+// default "en" resolves as pt-BR, pt, en, "".
+func LocaleChain(locale, defaultLocale string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+	add := func(locale string) {
+		for locale = NormalizeLocale(locale); locale != ""; {
+			if !seen[locale] {
+				seen[locale] = true
+				chain = append(chain, locale)
+			}
+			i := strings.LastIndex(locale, "-")
+			if i < 0 {
+				break
+			}
+			locale = locale[:i]
+		}
+	}
+	add(locale)
+	add(defaultLocale)
+	return append(chain, "")
+}
+
+// localeFuncs returns the formatting helpers bound to a locale:
+//
+//	formatDate <time or RFC 3339 string>         e.g. 31.12.2024 for de
+//	formatDateTime <time or RFC 3339 string>     date followed by 24h time
+//	formatNumber <number> [decimals]             e.g. 1.234,5 for de
+//	formatCurrency <number> <ISO currency code>  e.g. R$ 1.234,56 for pt-BR
+func localeFuncs(locale string) map[string]any {
+	tag, err := language.Parse(locale)
+	if err != nil || locale == "" {
+		tag = language.English
+	}
+	printer := message.NewPrinter(tag)
+	layout := dateLayout(locale)
+
+	return map[string]any{
+		"formatDate": func(value any) (string, error) {
+			t, err := toTime(value)
+			if err != nil {
+				return "", err
+			}
+			return t.Format(layout), nil
+		},
+		"formatDateTime": func(value any) (string, error) {
+			t, err := toTime(value)
+			if err != nil {
+				return "", err
+			}
+			return t.Format(layout + " 15:04"), nil
+		},
+		"formatNumber": func(value any, decimals ...int) (string, error) {
+			n, err := toFloat(value)
+			if err != nil {
+				return "", err
+			}
+			if len(decimals) > 0 {
+				return printer.Sprint(number.Decimal(n, number.Scale(decimals[0]))), nil
+			}
+			return printer.Sprint(number.Decimal(n)), nil
+		},
+		"formatCurrency": func(value any, code string) (string, error) {
+			n, err := toFloat(value)
+			if err != nil {
+				return "", err
+			}
+			unit, err := currency.ParseISO(code)
+			if err != nil {
+				return "", fmt.Errorf("invalid currency %q: %v", code, err)
+			}
+			return printer.Sprint(currency.Symbol(unit.Amount(n))), nil
+		},
+	}
+}
+
+// dateLayout returns the date layout of the most specific known locale in the chain of locale
+func dateLayout(locale string) string {
+	for _, candidate := range LocaleChain(locale, "") {
+		if layout, ok := dateLayouts[candidate]; ok {
+			return layout
+		}
+	}
+	return "2006-01-02"
+}
+
+// toTime converts a template value (time.Time or RFC 3339 string) to a time
+func toTime(value any) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, fmt.Errorf("cannot format a nil date")
+		}
+		return *v, nil
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, nil
+		}
+		return time.Parse("2006-01-02", v)
+	default:
+		return time.Time{}, fmt.Errorf("cannot format %T as a date", value)
+	}
+}
+
+// toFloat converts a template value (number or numeric string) to a float
+func toFloat(value any) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("cannot format %T as a number", value)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+templates/renderer.go
+Author: Akhil C
+Description: Renders stored notification templates with Go text/template and html/template for a given channel.
+*/
+
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/akhilckenshi/notification/internal/models"
+)
+
+// Rendered holds the content produced from a template variant
+type Rendered struct {
+	Locale  string `json:"locale,omitempty"`  // Locale of the variant that was rendered
+	Subject string `json:"subject,omitempty"` // Rendered subject
+	Text    string `json:"text,omitempty"`    // Rendered plain text body
+	HTML    string `json:"html,omitempty"`    // Rendered HTML body
+}
+
+// Body returns the main message body: the HTML body when present, the text body otherwise
+func (r Rendered) Body() string {
+	if r.HTML != "" {
+		return r.HTML
+	}
+	return r.Text
+}
+
+// VariantNotFoundError is returned when a template has no variant for the requested channel and locale
+type VariantNotFoundError struct {
+	TemplateID string
+	Channel    string
+	Locale     string
+}
+
+func (e *VariantNotFoundError) Error() string {
+	if e.Locale != "" {
+		return fmt.Sprintf("template %s has no %s variant for locale %s", e.TemplateID, e.Channel, e.Locale)
+	}
+	return fmt.Sprintf("template %s has no %s variant", e.TemplateID, e.Channel)
+}
+
+// funcs are the helper functions available inside every template
+var funcs = map[string]any{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"default": func(fallback, value any) any {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// ResolveVariant returns the variant of tpl for the channel that best matches locale,
+// following the chain described in LocaleChain
+func ResolveVariant(tpl *models.Template, channel, locale string) (*models.TemplateVariant, bool) {
+	for _, candidate := range LocaleChain(locale, tpl.DefaultLocale) {
+		if variant, ok := tpl.Variant(channel, candidate); ok {
+			return variant, true
+		}
+	}
+	return nil, false
+}
+
+// Render renders the variant of tpl for the given channel and locale with data
+func Render(tpl *models.Template, channel, locale string, data map[string]any) (Rendered, error) {
+	variant, ok := ResolveVariant(tpl, channel, locale)
+	if !ok {
+		return Rendered{}, &VariantNotFoundError{TemplateID: tpl.TemplateID, Channel: channel, Locale: locale}
+	}
+
+	// Formatting helpers follow the locale of the recipient, or of the variant if none was given
+	formatLocale := NormalizeLocale(locale)
+	if formatLocale == "" {
+		formatLocale = variant.Locale
+	}
+	helpers := templateFuncs(formatLocale)
+
+	rendered := Rendered{Locale: variant.Locale}
+	var err error
+	if rendered.Subject, err = renderText("subject", variant.Subject, helpers, data); err != nil {
+		return Rendered{}, err
+	}
+	if rendered.Text, err = renderText("text", variant.Text, helpers, data); err != nil {
+		return Rendered{}, err
+	}
+	if rendered.HTML, err = renderHTML("html", variant.HTML, helpers, data); err != nil {
+		return Rendered{}, err
+	}
+	return rendered, nil
+}
+
+// templateFuncs returns the common helpers together with the helpers bound to locale
+func templateFuncs(locale string) map[string]any {
+	helpers := localeFuncs(locale)
+	for name, fn := range funcs {
+		helpers[name] = fn
+	}
+	return helpers
+}
+
+// Validate parses every variant of tpl and reports the first syntax error.
+// Locale codes of the template are normalized in place.
+func Validate(tpl *models.Template) error {
+	if len(tpl.Variants) == 0 {
+		return fmt.Errorf("template must define at least one variant")
+	}
+
+	helpers := templateFuncs("")
+	seen := make(map[string]bool)
+	for i := range tpl.Variants {
+		variant := &tpl.Variants[i]
+		if variant.Channel == "" {
+			return fmt.Errorf("variant channel is required")
+		}
+		variant.Locale = NormalizeLocale(variant.Locale)
+		key := variant.Channel + "/" + variant.Locale
+		if seen[key] {
+			return fmt.Errorf("duplicate %s variant for locale %q", variant.Channel, variant.Locale)
+		}
+		seen[key] = true
+
+		if variant.Text == "" && variant.HTML == "" {
+			return fmt.Errorf("%s variant must define a text or html body", variant.Channel)
+		}
+		if _, err := texttemplate.New("subject").Funcs(helpers).Parse(variant.Subject); err != nil {
+			return fmt.Errorf("%s subject: %v", variant.Channel, err)
+		}
+		if _, err := texttemplate.New("text").Funcs(helpers).Parse(variant.Text); err != nil {
+			return fmt.Errorf("%s text: %v", variant.Channel, err)
+		}
+		if _, err := htmltemplate.New("html").Funcs(helpers).Parse(variant.HTML); err != nil {
+			return fmt.Errorf("%s html: %v", variant.Channel, err)
+		}
+	}
+	tpl.DefaultLocale = NormalizeLocale(tpl.DefaultLocale)
+	return nil
+}
+
+// renderText executes a text/template. Missing keys are reported as errors.
+func renderText(name, source string, helpers map[string]any, data map[string]any) (string, error) {
+	if source == "" {
+		return "", nil
+	}
+	tpl, err := texttemplate.New(name).Funcs(helpers).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s template: %v", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %v", name, err)
+	}
+	return buf.String(), nil
+}
+
+// renderHTML executes an html/template, escaping values for the HTML context
+func renderHTML(name, source string, helpers map[string]any, data map[string]any) (string, error) {
+	if source == "" {
+		return "", nil
+	}
+	tpl, err := htmltemplate.New(name).Funcs(helpers).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s template: %v", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %v", name, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,70 @@
+/*
+unsubscribe/token.go
+Author: Akhil C
+Description: Signed tokens identifying the recipient and category of one-click unsubscribe links (RFC 8058).
+*/
+
+package unsubscribe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// Path is the route of the public unsubscribe endpoint
+const Path = "/api/v1/unsubscribe"
+
+// ErrInvalidToken is returned for tokens that are malformed or were not signed with the secret
+var ErrInvalidToken = errors.New("invalid unsubscribe token")
+
+// Claims identify what a recipient unsubscribes from
+type Claims struct {
+	OrganizationID string `json:"o"` // Hex ID of the organization that sent the notification
+	Recipient      string `json:"r"` // Recipient address
+	Category       string `json:"c"` // Category of notifications the recipient opts out of
+}
+
+// Sign returns a token carrying claims, authenticated with an HMAC-SHA256 of secret.
+// Tokens are URL safe.
+func Sign(secret []byte, claims Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + signature(secret, encoded), nil
+}
+
+// Verify returns the claims of a token signed with secret
+func Verify(secret []byte, token string) (Claims, error) {
+	var claims Claims
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || len(secret) == 0 || !hmac.Equal([]byte(sig), []byte(signature(secret, encoded))) {
+		return claims, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return claims, ErrInvalidToken
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Recipient == "" || claims.Category == "" {
+		return claims, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// URL returns the unsubscribe link of a token on the service reachable at baseURL
+func URL(baseURL, token string) string {
+	return strings.TrimRight(baseURL, "/") + Path + "?token=" + url.QueryEscape(token)
+}
+
+// signature returns the encoded HMAC-SHA256 of payload
+func signature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
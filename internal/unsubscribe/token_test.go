@@ -0,0 +1,90 @@
+/*
+unsubscribe/token_test.go
+Author: Akhil C
+Description: Tests of signing and verifying unsubscribe tokens.
+*/
+
+package unsubscribe
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	secret := []byte("unsubscribe-secret")
+	claims := Claims{OrganizationID: "652f1c2e9b1e8a0001a1b2c3", Recipient: "jane@example.com", Category: "marketing"}
+	sign := func(secret []byte, claims Claims) string {
+		token, err := Sign(secret, claims)
+		if err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+		return token
+	}
+	valid := sign(secret, claims)
+	payload, sig, _ := strings.Cut(valid, ".")
+
+	// tamper replaces the recipient of the payload, keeping the signature
+	tamper := func(recipient string) string {
+		decoded, _ := base64.RawURLEncoding.DecodeString(payload)
+		changed := strings.Replace(string(decoded), claims.Recipient, recipient, 1)
+		return base64.RawURLEncoding.EncodeToString([]byte(changed)) + "." + sig
+	}
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		token   string
+		want    Claims
+		wantErr bool
+	}{
+		{name: "valid", secret: secret, token: valid, want: claims},
+		{name: "tampered payload", secret: secret, token: tamper("attacker@example.com"), wantErr: true},
+		{name: "tampered signature", secret: secret, token: payload + "." + strings.Repeat("A", len(sig)), wantErr: true},
+		{name: "signature of another payload", secret: secret, token: payload + "." + strings.Split(sign(secret, Claims{Recipient: "a@example.com", Category: "billing"}), ".")[1], wantErr: true},
+		{name: "other secret", secret: []byte("other-secret"), token: valid, wantErr: true},
+		{name: "empty secret", secret: nil, token: sign(nil, claims), wantErr: true},
+		{name: "missing signature", secret: secret, token: payload, wantErr: true},
+		{name: "empty token", secret: secret, token: "", wantErr: true},
+		{name: "invalid payload encoding", secret: secret, token: "!!!." + sig, wantErr: true},
+		{name: "missing category", secret: secret, token: sign(secret, Claims{Recipient: "jane@example.com"}), wantErr: true},
+		{name: "missing recipient", secret: secret, token: sign(secret, Claims{Category: "marketing"}), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Verify(tt.secret, tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("Verify() error = %v, want %v", err, ErrInvalidToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURL(t *testing.T) {
+	token, err := Sign([]byte("secret"), Claims{Recipient: "jane+news@example.com", Category: "marketing"})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	link, err := url.Parse(URL("https://notify.example.com/", token))
+	if err != nil {
+		t.Fatalf("invalid URL: %v", err)
+	}
+	if link.Path != Path {
+		t.Errorf("path = %q, want %q", link.Path, Path)
+	}
+	if got := link.Query().Get("token"); got != token {
+		t.Errorf("token = %q, want %q", got, token)
+	}
+}
@@ -15,8 +15,17 @@ type Configuration struct {
 	Logger                 LoggerConfig
 	Database               DatabaseConfig
 	WhatsApp               WhatsAppConfig
+	SMS                    SMSConfig
 	App                    AppConfig
 	Email                  EmailConfig
+	Kafka                  KafkaConfig
+	Idempotency            IdempotencyConfig
+	Preferences            PreferencesConfig
+	Unsubscribe            UnsubscribeConfig
+	Scheduler              SchedulerConfig
+	Dispatcher             DispatcherConfig
+	RateLimits             RateLimitConfig
+	Retry                  map[string]RetryConfig
 	DBURI                  string `mapstructure:"DBURI"`
 	DBName                 string `mapstructure:"DBNAME"`
 	DBConnCount            int    `mapstructure:"DBCONNCNT"`
@@ -24,6 +33,7 @@ type Configuration struct {
 	AppPort                string `mapstructure:"PORT"`
 	KafkaPort              string `mapstructure:"KAFKA_PORT"`
 	KafkaTopic             string `mapstructure:"KAFKA_TOPIC"`
+	KafkaGroupID           string `mapstructure:"KAFKA_GROUP_ID"`
 	AppEmailID             string `mapstructure:"APP_EMILID"`
 	AppEmailPassword       string `mapstructure:"APP_EMAIL_PWD"`
 	SMTPHost               string `mapstructure:"SMTP_HOST"`
@@ -32,6 +42,9 @@ type Configuration struct {
 	WhatsAppProviderKey    string `mapstructure:"WHATSAPP_PROVIDER_KEY"`
 	WhatsAppProviderSecret string `mapstructure:"WHATSAPP_PROVIDER_SECRET"`
 	WhatsAppFromNumber     string `mapstructure:"WHATSAPP_FROM_NUMBER"`
+	SMSFromNumber          string `mapstructure:"SMS_FROM_NUMBER"`
+	SMSMessagingServiceSID string `mapstructure:"SMS_MESSAGING_SERVICE_SID"`
+	UnsubscribeSecret      string `mapstructure:"UNSUBSCRIBE_SECRET"`
 }
 
 type LoggerConfig struct {
@@ -61,18 +74,123 @@ type WhatsAppConfig struct {
 	Key          string `mapstructure:"key"`
 	Secret       string `mapstructure:"secret"`
 	Number       string `mapstructure:"number"`
+	BaseURL      string `mapstructure:"baseUrl"` // Public base URL of the service, used for Twilio status callbacks and webhook signatures
+}
+
+// SMSConfig configures the SMS channel. Messages are sent with the Twilio account of
+// WHATSAPP_PROVIDER_KEY and WHATSAPP_PROVIDER_SECRET.
+type SMSConfig struct {
+	MaxSegments          int  `mapstructure:"maxSegments"`          // Longest message sent, in segments (default 10)
+	TruncateLongMessages bool `mapstructure:"truncateLongMessages"` // Truncate longer messages instead of rejecting them
 }
 
 type AppConfig struct {
-	WithSSL bool `mapstructure:"withssl"`
+	WithSSL      bool `mapstructure:"withssl"`
+	MaxBatchSize int  `mapstructure:"maxBatchSize"` // Maximum number of notifications accepted by the batch endpoint
+}
+
+type KafkaConfig struct {
+	InitialOffset     string `mapstructure:"initialOffset"`     // Where to start when the group has no committed offset: "oldest" or "newest"
+	RebalanceStrategy string `mapstructure:"rebalanceStrategy"` // Partition assignment strategy: "range", "roundrobin" or "sticky"
+	SessionTimeout    int    `mapstructure:"sessionTimeout"`    // Consumer group session timeout in seconds
+	CommitInterval    int    `mapstructure:"commitInterval"`    // Interval in seconds between offset commits
+	DeadLetterTopic   string `mapstructure:"deadLetterTopic"`   // Topic receiving notifications that could not be delivered
+}
+
+type IdempotencyConfig struct {
+	WindowMinutes int `mapstructure:"windowMinutes"` // How long an idempotency key suppresses duplicates, in minutes
+}
+
+// PreferencesConfig configures how recipient preferences are applied
+type PreferencesConfig struct {
+	BypassCategories []string `mapstructure:"bypassCategories"` // Categories sent regardless of preferences (default security, transactional)
+}
+
+// UnsubscribeConfig configures the unsubscribe links of marketing emails, signed with UNSUBSCRIBE_SECRET
+type UnsubscribeConfig struct {
+	BaseURL string `mapstructure:"baseUrl"` // Public base URL of the unsubscribe endpoint, whatsapp.baseUrl when empty
+}
+
+// SchedulerConfig configures the release of scheduled notifications
+type SchedulerConfig struct {
+	PollInterval int `mapstructure:"pollInterval"` // Seconds between checks for due notifications (default 5)
+	LeaseSeconds int `mapstructure:"leaseSeconds"` // Seconds a claimed schedule is reserved to one replica (default 60)
+}
+
+// DispatcherConfig configures the priority queues notifications are sent from
+type DispatcherConfig struct {
+	Workers    int                               `mapstructure:"workers"`    // Workers shared by every priority according to the weights (default 8)
+	QueueSize  int                               `mapstructure:"queueSize"`  // Notifications waiting per priority before submitters block (default 1000)
+	Priorities map[string]DispatchPriorityConfig `mapstructure:"priorities"` // Reserved workers and weight keyed by priority: high, medium, low
+
+	// Notifications queued or being sent at once; the Kafka consumer pauses fetching while they are reached
+	MaxInFlight        int            `mapstructure:"maxInFlight"`        // In total (default 500)
+	ChannelConcurrency map[string]int `mapstructure:"channelConcurrency"` // Per notification type (e.g. email: 50), only bounded by maxInFlight when not set
+}
+
+// DispatchPriorityConfig configures one priority under dispatcher.priorities
+type DispatchPriorityConfig struct {
+	Workers int `mapstructure:"workers"` // Workers only serving the priority (default 2 high, 1 medium, 0 low)
+	Weight  int `mapstructure:"weight"`  // Share of the shared workers when several priorities are waiting (default 6 high, 3 medium, 1 low)
+}
+
+// RateLimitConfig configures the token bucket rate limits of notifications under rateLimits.
+// Notifications over a limit are deferred until the bucket refills.
+type RateLimitConfig struct {
+	Channels      map[string]RateConfig `mapstructure:"channels"`      // Limits keyed by notification type
+	Providers     map[string]RateConfig `mapstructure:"providers"`     // Limits keyed by provider name (smtp, ses, sendgrid, twilio)
+	Organization  RateConfig            `mapstructure:"organization"`  // Limit applied to each organization separately
+	Organizations map[string]RateConfig `mapstructure:"organizations"` // Limits keyed by organization ID, replacing organization
+}
+
+// RateConfig is a token bucket rate limit
+type RateConfig struct {
+	PerSecond float64 `mapstructure:"perSecond"` // Messages per second; not limited when 0
+	Burst     int     `mapstructure:"burst"`     // Messages sent at once after an idle period (default perSecond, at least 1)
+}
+
+// RetryConfig is the retry policy of a channel, configured under retry.<notification type>
+type RetryConfig struct {
+	MaxAttempts int     `mapstructure:"maxAttempts"` // Total number of send attempts
+	BaseDelayMs int     `mapstructure:"baseDelayMs"` // Delay before the first retry in milliseconds
+	MaxDelayMs  int     `mapstructure:"maxDelayMs"`  // Upper bound of the retry delay in milliseconds
+	Jitter      float64 `mapstructure:"jitter"`      // Random fraction (0-1) applied to each delay
 }
 
 type EmailConfig struct {
-	Id       string `mapstructure:"id"`
-	Username string `mapstructure:"username"`
-	Pwd      string `mapstructure:"password"`
-	SmtpHost string `mapstructure:"smtpHost"`
-	SmtpPort int    `mapstructure:"smtpPort"`
+	Id                  string `mapstructure:"id"`
+	Username            string `mapstructure:"username"`
+	Pwd                 string `mapstructure:"password"`
+	SmtpHost            string `mapstructure:"smtpHost"`
+	SmtpPort            int    `mapstructure:"smtpPort"`
+	Security            string `mapstructure:"security"`            // Connection security: "starttls" (default), "tls" for implicit TLS (port 465) or "none"
+	Auth                string `mapstructure:"auth"`                // Authentication mechanism: "plain", "login" or "cram-md5"; best offered by the server when empty
+	InsecureSkipVerify  bool   `mapstructure:"insecureSkipVerify"`  // Skip verification of the server certificate
+	MaxConnections      int    `mapstructure:"maxConnections"`      // Maximum number of pooled SMTP connections
+	IdleTimeout         int    `mapstructure:"idleTimeout"`         // Seconds after which an idle connection is closed
+	HealthCheckInterval int    `mapstructure:"healthCheckInterval"` // Seconds of inactivity after which a connection is checked with NOOP before reuse
+	DialTimeout         int    `mapstructure:"dialTimeout"`         // Timeout in seconds to connect and authenticate
+	SendTimeout         int    `mapstructure:"sendTimeout"`         // Timeout in seconds to transmit a single message
+	WebhookToken        string `mapstructure:"webhookToken"`        // Token expected in the X-Webhook-Token header of bounce webhooks
+
+	// Email backends tried in order of priority; the SMTP settings above are used when empty
+	Providers []EmailProviderConfig `mapstructure:"providers"`
+}
+
+// EmailProviderConfig configures one email backend under email.providers
+type EmailProviderConfig struct {
+	Name      string `mapstructure:"name"`      // Name recorded on notifications sent through the provider, defaults to the type
+	Type      string `mapstructure:"type"`      // "smtp", "ses" or "sendgrid"
+	Priority  int    `mapstructure:"priority"`  // Providers with a lower priority are tried first
+	Endpoint  string `mapstructure:"endpoint"`  // API base URL (ses, sendgrid), the public API when empty
+	APIKey    string `mapstructure:"apiKey"`    // API key (sendgrid) or access key ID (ses)
+	APISecret string `mapstructure:"apiSecret"` // Secret access key (ses)
+	Region    string `mapstructure:"region"`    // AWS region (ses)
+	Timeout   int    `mapstructure:"timeout"`   // Request timeout in seconds (ses, sendgrid)
+	SmtpHost  string `mapstructure:"smtpHost"`  // Relay host (smtp), the email SMTP settings when empty
+	SmtpPort  int    `mapstructure:"smtpPort"`  // Relay port (smtp)
+	Username  string `mapstructure:"username"`  // Relay user (smtp)
+	Password  string `mapstructure:"password"`  // Relay password (smtp)
 }
 
 func InitConfig() (Configuration, error) {
@@ -115,9 +233,10 @@ func InitConfig() (Configuration, error) {
 
 	// Bind sensitive environment variables
 	envVars := []string{
-		"DBURI", "DBNAME", "PORT", "KAFKA_PORT", "KAFKA_TOPIC",
+		"DBURI", "DBNAME", "PORT", "KAFKA_PORT", "KAFKA_TOPIC", "KAFKA_GROUP_ID",
 		"APP_EMILID", "APP_USERNAME", "APP_PWD", "SMTP_HOST", "SMTP_PORT",
 		"WHATS_PROVIDER_URL", "WHATSAPP_PROVIDER_KEY", "WHATSAPP_PROVIDER_SECRET", "WHATSAPP_FROM_NUMBER",
+		"SMS_FROM_NUMBER", "SMS_MESSAGING_SERVICE_SID", "UNSUBSCRIBE_SECRET",
 	}
 	for _, envVar := range envVars {
 		if err := viper.BindEnv(envVar); err != nil {